@@ -0,0 +1,62 @@
+// Package breached checks the passwords against a local copy of a breached password corpus.
+//
+// The corpus is split by the first 5 hexadecimal characters of the SHA-1 of the passwords, the
+// k-anonymity ranges of Have I Been Pwned: the file 5BAA6.txt has the lines SUFFIX:COUNT of the
+// hashes starting with 5BAA6. The downloader of Have I Been Pwned writes this layout.
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const prefixLength = 5
+
+// PrefixDir is a directory of range files, the missing ranges have no breached password
+type PrefixDir struct {
+	Dir string
+	// passwords seen fewer times are accepted, 1 rejects any breached password
+	MinCount int
+}
+
+func NewPrefixDir(dir string) *PrefixDir {
+	return &PrefixDir{Dir: dir, MinCount: 1}
+}
+
+func (p *PrefixDir) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(p.Dir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 || !strings.EqualFold(line[:colon], suffix) {
+			continue
+		}
+
+		count, err := strconv.Atoi(line[colon+1:])
+		if err != nil {
+			// a corpus without the counts
+			count = 1
+		}
+		return count >= p.MinCount, nil
+	}
+
+	return false, scanner.Err()
+}
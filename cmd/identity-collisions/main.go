@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"todo/domain"
+	"todo/postgres"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// Reports the users whose email or username can't be told apart once normalized, with the keys the
+// unique indexes of the users enforce (domain.EmailKey and domain.UsernameKey). Run it before the
+// migrations of the indexes: the app can't fill the keys on startup while two users collide.
+// Exits with 1 when there are collisions.
+func main() {
+	DB := postgres.New(&pg.Options{
+		User:     "postgres",
+		Password: "1234",
+		Database: "todo_dev",
+	})
+
+	defer DB.Close()
+
+	var users []*domain.User
+	err := DB.Model(&users).Column("id", "username", "email").Order("id").Select()
+	if err != nil {
+		log.Fatalf("cannot list the users %v", err)
+	}
+
+	emails := collisions(users, func(u *domain.User) string { return domain.EmailKey(u.Email) })
+	usernames := collisions(users, func(u *domain.User) string { return domain.UsernameKey(u.Username) })
+
+	report("email", emails)
+	report("username", usernames)
+
+	if len(emails) > 0 || len(usernames) > 0 {
+		os.Exit(1)
+	}
+
+	fmt.Println("no collisions")
+}
+
+// collisions groups the users by key and keeps the groups of more than one user
+func collisions(users []*domain.User, key func(*domain.User) string) map[string][]*domain.User {
+	groups := map[string][]*domain.User{}
+	for _, user := range users {
+		k := key(user)
+		groups[k] = append(groups[k], user)
+	}
+
+	for k, group := range groups {
+		if len(group) < 2 {
+			delete(groups, k)
+		}
+	}
+
+	return groups
+}
+
+func report(field string, groups map[string][]*domain.User) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("%d users share the %s %q\n", len(groups[k]), field, k)
+		for _, user := range groups[k] {
+			fmt.Printf("  id=%d username=%q email=%q\n", user.ID, user.Username, user.Email)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// Kinds of account jobs
+const (
+	AccountJobExport   = "export"
+	AccountJobDeletion = "deletion"
+)
+
+// Statuses of the account jobs
+const (
+	AccountJobPending   = "pending"
+	AccountJobRunning   = "running"
+	AccountJobDone      = "done"
+	AccountJobFailed    = "failed"
+	AccountJobCancelled = "cancelled"
+)
+
+const (
+	// time to change your mind after asking for the deletion of the account
+	AccountDeletionGrace = 7 * 24 * time.Hour
+	// the exports are deleted after this
+	ExportTTL = 7 * 24 * time.Hour
+	// a job running for longer than this is run again, the instance running it likely died
+	AccountJobLease = time.Hour
+
+	accountJobsBatch = 10
+)
+
+// An AccountJob is a data export or an account deletion running in the background
+type AccountJob struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	RunAfter   time.Time  `json:"runAfter"`
+	FileName   string     `json:"-" pg:",use_zero"`
+	Error      string     `json:"error,omitempty" pg:",use_zero"`
+	FinishedAt *time.Time `json:"finishedAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"` // of the export file
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+func (j *AccountJob) IsOwner(user *User) bool {
+	return j.UserID == user.ID
+}
+
+// A FileStore keeps the exports until they are downloaded, see the storage package
+type FileStore interface {
+	Save(name string, data []byte) error
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+// RequestDataExport queues an export of everything tied to the user, or returns the one already queued
+func (d *Domain) RequestDataExport(user *User) (*AccountJob, error) {
+	if job, err := d.DB.AccountJobRepo.GetPending(user.ID, AccountJobExport); err == nil {
+		return job, nil
+	}
+
+	return d.DB.AccountJobRepo.Create(&AccountJob{
+		UserID:   user.ID,
+		Kind:     AccountJobExport,
+		Status:   AccountJobPending,
+		RunAfter: time.Now(),
+	})
+}
+
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+}
+
+func (p *DeleteAccountPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("password", p.Password)
+
+	return v.IsValid(), v.errors
+}
+
+// RequestAccountDeletion deletes the account after the grace period, unless it's cancelled before
+func (d *Domain) RequestAccountDeletion(user *User, payload DeleteAccountPayload) (*AccountJob, error) {
+	if err := d.checkPassword(user, payload.Password); err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	if job, err := d.DB.AccountJobRepo.GetPending(user.ID, AccountJobDeletion); err == nil {
+		return job, nil
+	}
+
+	job, err := d.DB.AccountJobRepo.Create(&AccountJob{
+		UserID:   user.ID,
+		Kind:     AccountJobDeletion,
+		Status:   AccountJobPending,
+		RunAfter: time.Now().Add(AccountDeletionGrace),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nyour account and all its data will be deleted on %s. Log in and cancel the deletion before then if you changed your mind.\n",
+			user.Username, job.RunAfter.In(user.Location()).Format("January 2, 2006 15:04 MST")),
+	})
+
+	return job, nil
+}
+
+func (d *Domain) CancelAccountDeletion(user *User) (*AccountJob, error) {
+	job, err := d.DB.AccountJobRepo.GetPending(user.ID, AccountJobDeletion)
+	if err != nil {
+		return nil, err
+	}
+
+	// the job may start running between the read and the update
+	cancelled, err := d.DB.AccountJobRepo.Cancel(job)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cancelled {
+		return nil, ErrAccountJobRunning
+	}
+
+	return job, nil
+}
+
+func (d *Domain) ListAccountJobs(user *User) ([]*AccountJob, error) {
+	return d.DB.AccountJobRepo.ListByUser(user.ID)
+}
+
+func (d *Domain) GetAccountJobByID(id int64) (*AccountJob, error) {
+	return d.DB.AccountJobRepo.GetByID(id)
+}
+
+// OpenExport returns the zip of a finished export
+func (d *Domain) OpenExport(job *AccountJob, user *User) (io.ReadCloser, error) {
+	if err := mustOwn(job, user); err != nil {
+		return nil, err
+	}
+
+	if job.Kind != AccountJobExport || job.Status != AccountJobDone || job.FileName == "" {
+		return nil, ErrExportNotReady
+	}
+
+	return d.Files.Open(job.FileName)
+}
+
+// RunAccountJobs runs the due jobs and deletes the expired exports. It runs as a background job
+func (d *Domain) RunAccountJobs(now time.Time) error {
+	jobs, err := d.DB.AccountJobRepo.ClaimDue(now, accountJobsBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		switch job.Kind {
+		case AccountJobExport:
+			err = d.runExport(job)
+		case AccountJobDeletion:
+			err = d.runDeletion(job)
+		default:
+			err = fmt.Errorf("unknown account job %q", job.Kind)
+		}
+
+		if err != nil {
+			log.Printf("account job %d failed: %v", job.ID, err)
+			d.finishAccountJob(job, AccountJobFailed, err.Error())
+		}
+	}
+
+	return d.deleteExpiredExports(now)
+}
+
+func (d *Domain) runExport(job *AccountJob) error {
+	user, err := d.DB.UserRepo.GetByID(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	archive, err := d.exportUserData(user)
+	if err != nil {
+		return err
+	}
+
+	random, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+
+	job.FileName = fmt.Sprintf("export-%d-%s.zip", user.ID, random)
+	if err := d.Files.Save(job.FileName, archive); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ExportTTL)
+	job.ExpiresAt = &expiresAt
+
+	if err := d.finishAccountJob(job, AccountJobDone, ""); err != nil {
+		return err
+	}
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nthe export of your data is ready, download it from your account settings. It will be deleted in %v.\n",
+			user.Username, ExportTTL),
+	})
+
+	return nil
+}
+
+// runDeletion deletes the user. Everything tied to them goes too (ON DELETE CASCADE), the job included
+func (d *Domain) runDeletion(job *AccountJob) error {
+	user, err := d.DB.UserRepo.GetByID(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := d.DB.AccountJobRepo.ListByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range jobs {
+		if other.FileName != "" {
+			if err := d.Files.Delete(other.FileName); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.LogoutEverywhere(user); err != nil {
+		return err
+	}
+
+	if err := d.DB.UserRepo.Delete(user); err != nil {
+		return err
+	}
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "Your account was deleted",
+		Body:    fmt.Sprintf("Hi %s,\n\nyour account and all its data were deleted.\n", user.Username),
+	})
+
+	return nil
+}
+
+func (d *Domain) finishAccountJob(job *AccountJob, status, message string) error {
+	now := time.Now()
+	job.Status = status
+	job.Error = message
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+
+	_, err := d.DB.AccountJobRepo.Update(job)
+	return err
+}
+
+func (d *Domain) deleteExpiredExports(now time.Time) error {
+	jobs, err := d.DB.AccountJobRepo.ListExpired(now)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if err := d.Files.Delete(job.FileName); err != nil {
+			return err
+		}
+
+		job.FileName = ""
+		job.UpdatedAt = now
+
+		if _, err := d.DB.AccountJobRepo.Update(job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
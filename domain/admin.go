@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"time"
+)
+
+// The admin api lets the support staff help the users. Every action is written to the audit log,
+// the changes in the same transaction as their entry
+
+type UserFilter struct {
+	Query    string // part of the username or email
+	Role     string
+	Disabled *bool
+	Offset   int
+}
+
+func (d *Domain) AdminSearchUsers(admin *User, filter UserFilter) ([]*User, error) {
+	if filter.Role != "" {
+		v := NewValidator()
+		if !v.MustBeOneOf("role", filter.Role, Roles) {
+			return nil, ErrValidation{Errors: v.errors}
+		}
+	}
+
+	users, err := d.DB.UserRepo.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"query": filter.Query, "role": filter.Role, "offset": filter.Offset}
+	if err := d.audit(admin, AuditSearchUsers, nil, details); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (d *Domain) AdminGetUser(admin *User, target *User) (*User, error) {
+	if err := d.audit(admin, AuditViewUser, target, nil); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (d *Domain) AdminListUserTodos(admin *User, target *User, filter TodoFilter) ([]*Todo, error) {
+	todos, err := d.ListTodos(target, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.audit(admin, AuditViewUserTodos, target, nil); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+type DisableUserPayload struct {
+	Reason string `json:"reason"`
+}
+
+func (p *DisableUserPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("reason", p.Reason)
+
+	return v.IsValid(), v.errors
+}
+
+// AdminDisableUser blocks the logins of the user and logs them out everywhere
+func (d *Domain) AdminDisableUser(admin *User, target *User, payload DisableUserPayload) (*User, error) {
+	if admin.ID == target.ID {
+		return nil, ErrCannotDisableSelf
+	}
+
+	err := d.inTransaction(func(tx *Domain) error {
+		if !target.IsDisabled() {
+			now := time.Now()
+			target.DisabledAt = &now
+			target.UpdatedAt = now
+
+			user, err := tx.DB.UserRepo.UpdateDisabled(target)
+			if err != nil {
+				return err
+			}
+			target = user
+
+			if err := tx.LogoutEverywhere(target); err != nil {
+				return err
+			}
+		}
+
+		return tx.audit(admin, AuditDisableUser, target, map[string]interface{}{"reason": payload.Reason})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (d *Domain) AdminEnableUser(admin *User, target *User) (*User, error) {
+	err := d.inTransaction(func(tx *Domain) error {
+		if target.IsDisabled() {
+			target.DisabledAt = nil
+			target.UpdatedAt = time.Now()
+
+			user, err := tx.DB.UserRepo.UpdateDisabled(target)
+			if err != nil {
+				return err
+			}
+			target = user
+		}
+
+		return tx.audit(admin, AuditEnableUser, target, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// AdminForcePasswordReset replaces the password with a random one, logs the user out
+// everywhere and sends them the link to choose a new password once it's committed
+func (d *Domain) AdminForcePasswordReset(admin *User, target *User) error {
+	random, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	password, err := d.setPassword(random)
+	if err != nil {
+		return err
+	}
+
+	err = d.inTransaction(func(tx *Domain) error {
+		target.Password = *password
+		target.UpdatedAt = time.Now()
+
+		if err := tx.DB.UserRepo.UpdatePassword(target); err != nil {
+			return err
+		}
+
+		if err := tx.LogoutEverywhere(target); err != nil {
+			return err
+		}
+
+		return tx.audit(admin, AuditForcePasswordReset, target, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.sendPasswordReset(target)
+}
+
+func (d *Domain) AdminListAuditLogs(admin *User, filter AuditLogFilter) ([]*AuditLog, error) {
+	entries, err := d.DB.AuditLogRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.audit(admin, AuditViewAuditLog, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ArchiveRule archives the completed todos of a user once they are CompletedAfterDays old
+type ArchiveRule struct {
+	UserID             int64 `json:"-" pg:",pk"`
+	Enabled            bool  `json:"enabled" pg:",use_zero"`
+	CompletedAfterDays int   `json:"completedAfterDays"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ArchiveRulePayload struct {
+	Enabled            bool `json:"enabled"`
+	CompletedAfterDays int  `json:"completedAfterDays"`
+}
+
+func (a *ArchiveRulePayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeAtLeast("completedAfterDays", a.CompletedAfterDays, 1)
+
+	return v.IsValid(), v.errors
+}
+
+// GetArchiveRule returns the rule of the user. Users without one get a disabled rule
+func (d *Domain) GetArchiveRule(user *User) (*ArchiveRule, error) {
+	rule, err := d.DB.ArchiveRuleRepo.GetByUser(user.ID)
+	if errors.Is(err, ErrNoResult) {
+		return &ArchiveRule{UserID: user.ID, CompletedAfterDays: 7}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (d *Domain) SetArchiveRule(payload ArchiveRulePayload, user *User) (*ArchiveRule, error) {
+	rule, err := d.DB.ArchiveRuleRepo.Save(&ArchiveRule{
+		UserID:             user.ID,
+		Enabled:            payload.Enabled,
+		CompletedAfterDays: payload.CompletedAfterDays,
+		UpdatedAt:          time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (d *Domain) ArchiveTodo(todo *Todo) (*Todo, error) {
+	if todo.IsArchived() {
+		return todo, nil
+	}
+
+	now := time.Now()
+	todo.ArchivedAt = &now
+	todo.UpdatedAt = now
+
+	return d.DB.TodoRepo.Update(todo)
+}
+
+func (d *Domain) UnarchiveTodo(todo *Todo) (*Todo, error) {
+	if !todo.IsArchived() {
+		return todo, nil
+	}
+
+	// UnarchivedAt restarts the clock of the archive rule, otherwise the job would archive it again right away
+	now := time.Now()
+	todo.ArchivedAt = nil
+	todo.UnarchivedAt = &now
+	todo.UpdatedAt = now
+
+	return d.DB.TodoRepo.Update(todo)
+}
+
+// ListArchivedTodos browses the archive with the same filters as ListTodos
+func (d *Domain) ListArchivedTodos(user *User, filter TodoFilter) ([]*Todo, error) {
+	filter.OnlyArchived = true
+
+	return d.ListTodos(user, filter)
+}
+
+// ArchiveCompletedTodos applies the archive rules of every user. It runs as a background job
+func (d *Domain) ArchiveCompletedTodos(now time.Time) error {
+	_, err := d.DB.TodoRepo.ArchiveCompleted(now)
+	return err
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AssignmentPending   = "pending"
+	AssignmentAccepted  = "accepted"
+	AssignmentDeclined  = "declined"
+	AssignmentCancelled = "cancelled" // the creator assigned the todo to someone else or unassigned it
+)
+
+// An Assignment asks a user to work on a todo. Only once accepted the user becomes the assignee of the todo
+type Assignment struct {
+	ID           int64  `json:"id"`
+	Status       string `json:"status"`
+	TodoID       int64  `json:"todoId"`
+	AssigneeID   int64  `json:"assigneeId"`
+	AssignedByID int64  `json:"assignedById"`
+
+	Todo *Todo `json:"todo,omitempty" pg:"rel:has-one"`
+
+	RespondedAt *time.Time `json:"respondedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (a *Assignment) IsOwner(user *User) bool {
+	return a.AssigneeID == user.ID
+}
+
+type AssignTodoPayload struct {
+	Username string `json:"username"`
+}
+
+func (a *AssignTodoPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("username", a.Username)
+
+	return v.IsValid(), v.errors
+}
+
+// AssignTodo creates a pending assignment for the user. Only the creator of the todo can assign it
+func (d *Domain) AssignTodo(todo *Todo, payload AssignTodoPayload, user *User) (*Assignment, error) {
+	if !todo.IsCreator(user) {
+		return nil, ErrForbidden
+	}
+
+	assignee, err := d.DB.UserRepo.GetByUsername(payload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignee.ID == user.ID {
+		return nil, ErrCannotAssignToSelf
+	}
+
+	// a new assignment replaces the one waiting for an answer
+	if err := d.DB.AssignmentRepo.CancelPending(todo.ID); err != nil {
+		return nil, err
+	}
+
+	assignment, err := d.DB.AssignmentRepo.Create(&Assignment{
+		Status:       AssignmentPending,
+		TodoID:       todo.ID,
+		AssigneeID:   assignee.ID,
+		AssignedByID: user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	todoID := todo.ID
+	d.notify(assignee.ID, Notification{
+		Kind:    NotificationTodoAssigned,
+		Message: fmt.Sprintf("%s assigned you %q", user.Username, todo.Title),
+		TodoID:  &todoID,
+	})
+
+	return assignment, nil
+}
+
+// UnassignTodo removes the assignee and cancels the pending assignment
+func (d *Domain) UnassignTodo(todo *Todo, user *User) (*Todo, error) {
+	if !todo.IsCreator(user) {
+		return nil, ErrForbidden
+	}
+
+	if err := d.DB.AssignmentRepo.CancelPending(todo.ID); err != nil {
+		return nil, err
+	}
+
+	todo.AssigneeID = nil
+	todo.UpdatedAt = time.Now()
+
+	return d.DB.TodoRepo.Update(todo)
+}
+
+func (d *Domain) GetAssignmentByID(id int64) (*Assignment, error) {
+	assignment, err := d.DB.AssignmentRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// ListPendingAssignments returns the assignments waiting for an answer of the user
+func (d *Domain) ListPendingAssignments(user *User) ([]*Assignment, error) {
+	assignments, err := d.DB.AssignmentRepo.ListPendingByAssignee(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (d *Domain) AcceptAssignment(assignment *Assignment, user *User) (*Assignment, error) {
+	if err := d.respond(assignment, user, AssignmentAccepted); err != nil {
+		return nil, err
+	}
+
+	todo, err := d.DB.TodoRepo.GetByID(assignment.TodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	todo.AssigneeID = &user.ID
+	todo.UpdatedAt = time.Now()
+
+	if _, err := d.DB.TodoRepo.Update(todo); err != nil {
+		return nil, err
+	}
+
+	return d.DB.AssignmentRepo.Update(assignment)
+}
+
+func (d *Domain) DeclineAssignment(assignment *Assignment, user *User) (*Assignment, error) {
+	if err := d.respond(assignment, user, AssignmentDeclined); err != nil {
+		return nil, err
+	}
+
+	return d.DB.AssignmentRepo.Update(assignment)
+}
+
+func (d *Domain) respond(assignment *Assignment, user *User, status string) error {
+	if err := mustOwn(assignment, user); err != nil {
+		return err
+	}
+
+	if assignment.Status != AssignmentPending {
+		return ErrAssignmentNotPending
+	}
+
+	now := time.Now()
+	assignment.Status = status
+	assignment.RespondedAt = &now
+	assignment.UpdatedAt = now
+
+	return nil
+}
+
+// the views of the todos listing
+const (
+	TodoViewAll      = ""         // created by me or assigned to me
+	TodoViewCreated  = "created"  // created by me
+	TodoViewAssigned = "assigned" // assigned to me
+)
+
+var TodoViews = []string{TodoViewCreated, TodoViewAssigned}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// Actions written to the audit log
+const (
+	AuditSearchUsers        = "users.search"
+	AuditViewUser           = "user.view"
+	AuditViewUserTodos      = "user.todos.view"
+	AuditDisableUser        = "user.disable"
+	AuditEnableUser         = "user.enable"
+	AuditForcePasswordReset = "user.password_reset"
+	AuditViewAuditLog       = "audit_log.view"
+)
+
+// An AuditLog entry records an action of an admin, and the user it was about
+type AuditLog struct {
+	ID           int64                  `json:"id"`
+	ActorID      *int64                 `json:"actorId"`
+	Action       string                 `json:"action"`
+	TargetUserID *int64                 `json:"targetUserId"`
+	Details      map[string]interface{} `json:"details"`
+	CreatedAt    time.Time              `json:"createdAt"`
+}
+
+type AuditLogFilter struct {
+	ActorID      *int64
+	TargetUserID *int64
+	Action       string
+	Offset       int
+}
+
+// audit writes the entry. The actions fail when it can't, there are no admin actions without trace
+func (d *Domain) audit(actor *User, action string, target *User, details map[string]interface{}) error {
+	entry := &AuditLog{
+		ActorID: &actor.ID,
+		Action:  action,
+		Details: details,
+	}
+
+	if target != nil {
+		entry.TargetUserID = &target.ID
+	}
+
+	if entry.Details == nil {
+		entry.Details = map[string]interface{}{}
+	}
+
+	_, err := d.DB.AuditLogRepo.Create(entry)
+	return err
+}
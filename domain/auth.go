@@ -1,14 +1,13 @@
 package domain
 
 import (
-	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/dgrijalva/jwt-go/request"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // This struct will capture the client data request
@@ -17,39 +16,52 @@ type RegisterPayload struct {
 	Password        string `json:"password"`
 	ConfirmPassword string `json:"confirmPassword"`
 	Username        string `json:"username"`
+	Timezone        string `json:"timezone"` // optional, defaults to UTC
 }
 
-// Function to validate the request from client
-func (r *RegisterPayload) IsValid() (bool, map[string]string) {
-	v := NewValidator()
-
-	// Email verification
-	v.MustBeNotEmpty("email", r.Email)
-	v.MustBeValidEmail("email", r.Email)
-
+// mustBeValidNewPassword checks the fields of every password the user chooses (register, reset...),
+// the password policy applies in the domain since it needs the user (see validateNewPassword)
+func (v *Validator) mustBeValidNewPassword(password, confirmPassword string) {
 	// Password validation
-	v.MustBeLongerThan("password", r.Password, 6)
-	v.MustBeNotEmpty("password", r.Password)
+	v.MustBeNotEmpty("password", password)
 
 	//ConfirmPassword validation
-	v.MustBeNotEmpty("confirmPassword", r.ConfirmPassword)
+	v.MustBeNotEmpty("confirmPassword", confirmPassword)
 	v.MustMatch(ElementMatcher{
 		field: "confirmPassword",
-		value: r.ConfirmPassword,
+		value: confirmPassword,
 	},
 		ElementMatcher{
 			field: "password",
-			value: r.Password,
+			value: password,
 		})
+}
+
+// Function to validate the request from client
+func (r *RegisterPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	// Email verification
+	v.MustBeNotEmpty("email", r.Email)
+	v.MustBeValidEmail("email", r.Email)
+
+	v.mustBeValidNewPassword(r.Password, r.ConfirmPassword)
 
 	// Username validation
 	v.MustBeLongerThan("username", r.Username, 3)
 	v.MustBeNotEmpty("username", r.Username)
+	v.mustBeSafeUsername("username", r.Username)
+
+	if r.Timezone != "" {
+		v.MustBeValidTimezone("timezone", r.Timezone)
+	}
 
 	return v.IsValid(), v.errors
 }
 
 func (d *Domain) Register(payload RegisterPayload) (*User, error) {
+	payload.Email = d.normalizeEmail(payload.Email)
+	payload.Username = NormalizeUsername(payload.Username)
 
 	// First, we check that user exists, so for that we called the functions of the interface
 	userExist, _ := d.DB.UserRepo.GetByEmail(payload.Email)
@@ -57,14 +69,19 @@ func (d *Domain) Register(payload RegisterPayload) (*User, error) {
 		return nil, ErrUserWithEmailAlreadyExist
 	}
 
-	userExist, _ = d.DB.UserRepo.GetByUsername(payload.Username)
+	// bob, Bob and bоb (Cyrillic о) are the same username
+	userExist, _ = d.DB.UserRepo.GetByUsernameKey(UsernameKey(payload.Username))
 	if userExist != nil {
 		return nil, ErrUserWithUsernameAlreadyExist
 	}
 
+	err := d.validateNewPassword(&User{Username: payload.Username, Email: payload.Email}, payload.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	//if defined, we create our password string and the data
 	password, err := d.setPassword(payload.Password)
-	fmt.Println(*password)
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +90,7 @@ func (d *Domain) Register(payload RegisterPayload) (*User, error) {
 		Username: payload.Username,
 		Email:    payload.Email,
 		Password: *password,
+		Timezone: payload.Timezone,
 	}
 
 	user, err := d.DB.UserRepo.Create(data)
@@ -80,6 +98,15 @@ func (d *Domain) Register(payload RegisterPayload) (*User, error) {
 		return nil, err
 	}
 
+	if err := d.rememberPassword(user); err != nil {
+		log.Printf("cannot keep the password history of user %d: %v", user.ID, err)
+	}
+
+	// the account works right away, but with limited access until the email is verified
+	if err := d.SendVerificationEmail(user); err != nil {
+		log.Printf("cannot send the verification email to user %d: %v", user.ID, err)
+	}
+
 	return user, nil
 
 	// This function returns the user as normal if no errors found AND creates Repo
@@ -102,34 +129,49 @@ func (l *LoginPayload) IsValid() (bool, map[string]string) {
 
 }
 
-func (d *Domain) Login(payload LoginPayload) (*User, error) {
+// Login checks the password. The failures of the account and of the client address (ip, empty when
+// unknown) are throttled, see login_throttle.go
+func (d *Domain) Login(payload LoginPayload, ip string) (*User, error) {
+	now := time.Now()
+
+	if err := d.checkLoginThrottle(payload.Email, ip, now); err != nil {
+		return nil, err
+	}
+
+	user, err := d.DB.UserRepo.GetByEmail(d.normalizeEmail(payload.Email))
+	if err == nil && user != nil {
+		err = d.checkPassword(user, payload.Password)
+	} else {
+		d.verifyPassword(dummyPasswordHash, payload.Password)
+	}
 
-	user, err := d.DB.UserRepo.GetByEmail(payload.Email)
 	if err != nil || user == nil {
+		if err := d.recordLoginFailure(payload.Email, ip, now); err != nil {
+			log.Printf("cannot record the failed login: %v", err)
+		}
 		return nil, ErrInvalidCredential
 	}
 
-	err = user.checkPassword(payload.Password)
+	if err := d.recordLoginSuccess(payload.Email); err != nil {
+		log.Printf("cannot reset the failed logins: %v", err)
+	}
 
-	if err != nil {
-		fmt.Printf("no password")
-		return nil, ErrInvalidCredential
+	// hashes of an older algorithm or with older parameters are upgraded while we have the password
+	if err := d.rehashPassword(user, payload.Password); err != nil {
+		log.Printf("cannot rehash the password of user %d: %v", user.ID, err)
 	}
 
 	return user, nil
 }
 
+// setPassword returns the hash of the password, see password_hash.go
 func (d *Domain) setPassword(password string) (*string, error) {
-	bytePassword := []byte(password)
-	passwordHash, err := bcrypt.GenerateFromPassword(bytePassword, bcrypt.DefaultCost)
-
+	passwordHash, err := d.passwordHasher().Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
-	password = string(passwordHash)
-	fmt.Println(password)
-	return &password, nil
+	return &passwordHash, nil
 }
 
 // We need a way to extracft the token from the requested object
@@ -158,14 +200,153 @@ var authExtractor = &request.MultiExtractor{
 	authHeaderExtractor,
 }
 
+// BearerToken returns the token of the Authorization header, a JWT or a personal access token
+func BearerToken(r *http.Request) (string, error) {
+	return authExtractor.ExtractToken(r)
+}
+
 // Parse the token from the http request -
 // Note: First we auth the user, if so the response will be the auth header. After that, to access to the API
 // this token must be used to certify the user is who it is.
-func ParseToken(r *http.Request) (*jwt.Token, error) {
-	token, err := request.ParseFromRequest(r, authExtractor, func(t *jwt.Token) (interface{}, error) {
-		b := []byte(os.Getenv("JWT_SECRET"))
-		return b, nil
-	})
+func (d *Domain) ParseToken(r *http.Request) (*jwt.Token, error) {
+	tokenString, err := authExtractor.ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Keys.Parse(tokenString)
+}
+
+// AuthenticateToken returns the user of a parsed access token. Besides the signature and expiration
+// the token must be an access token, not logged out (jti) and of the current token generation of the user
+func (d *Domain) AuthenticateToken(token *jwt.Token) (*User, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	id, _ := claims["id"].(float64) // the claim is in float, but our userID is int64
+	jti, _ := claims["jti"].(string)
+	generation, _ := claims["gen"].(float64)
+
+	// e.g a mfa pending token is not enough to use the api
+	if jti == "" || claims["typ"] != TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+
+	if d.Revocations != nil {
+		revoked, err := d.Revocations.IsRevoked(jti)
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	user, err := d.DB.UserRepo.GetByID(int64(id))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if int64(generation) != user.TokenGeneration {
+		return nil, ErrInvalidToken
+	}
+
+	if err := d.checkSession(user, claims); err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	return user, nil
+}
+
+// LogoutPayload optionally carries the refresh token, so it stops working too
+type LogoutPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Logout revokes the access token until it expires, and the refresh token family if we got one
+func (d *Domain) Logout(token *jwt.Token, payload LogoutPayload) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+
+	if d.Revocations != nil {
+		if err := d.Revocations.Revoke(jti, time.Unix(int64(exp), 0)); err != nil {
+			return err
+		}
+	}
+
+	// the session of the token ends too, AuthenticateToken already checked it's one of the user
+	if sid := SessionIDFromToken(token); sid != 0 {
+		session, err := d.DB.SessionRepo.GetByID(sid)
+		if err != nil {
+			return err
+		}
+
+		if err := d.DB.SessionRepo.Revoke(session); err != nil {
+			return err
+		}
+
+		if err := d.DB.RefreshTokenRepo.RevokeFamily(session.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	if payload.RefreshToken == "" {
+		return nil
+	}
+
+	refresh, err := d.DB.RefreshTokenRepo.GetByHash(hashToken(payload.RefreshToken))
+	if err != nil {
+		// an unknown refresh token has nothing to revoke
+		return nil
+	}
+
+	if id, _ := claims["id"].(float64); refresh.UserID != int64(id) {
+		return nil
+	}
+
+	return d.DB.RefreshTokenRepo.RevokeFamily(refresh.FamilyID)
+}
+
+// LogoutEverywhere invalidates every access and refresh token of the user. The personal access
+// tokens of the scripts keep working, only LogoutAll revokes them when asked to
+func (d *Domain) LogoutEverywhere(user *User) error {
+	if err := d.DB.UserRepo.IncrementTokenGeneration(user); err != nil {
+		return err
+	}
+
+	if err := d.DB.SessionRepo.RevokeByUser(user.ID); err != nil {
+		return err
+	}
+
+	return d.DB.RefreshTokenRepo.RevokeByUser(user.ID)
+}
+
+// LogoutAllPayload opts in to revoking the personal access tokens too, the scripts using them stop working
+type LogoutAllPayload struct {
+	PersonalAccessTokens bool `json:"personalAccessTokens"`
+}
+
+// LogoutAll logs the user out of every device, e.g when the account may be compromised
+func (d *Domain) LogoutAll(user *User, payload LogoutAllPayload) error {
+	if err := d.LogoutEverywhere(user); err != nil {
+		return err
+	}
+
+	if !payload.PersonalAccessTokens {
+		return nil
+	}
 
-	return token, err
+	return d.DB.PersonalAccessTokenRepo.DeleteByUser(user.ID)
 }
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+type Comment struct {
+	ID     int64  `json:"id"`
+	Body   string `json:"body"`
+	TodoID int64  `json:"todoId"`
+	UserID int64  `json:"userId"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CreateCommentPayload struct {
+	Body string `json:"body"`
+}
+
+func (c *CreateCommentPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("body", c.Body)
+
+	return v.IsValid(), v.errors
+}
+
+// CreateComment adds a comment to the todo and notifies the users mentioned in it
+func (d *Domain) CreateComment(payload CreateCommentPayload, todo *Todo, user *User) (*Comment, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	comment, err := d.DB.CommentRepo.Create(&Comment{
+		Body:   payload.Body,
+		TodoID: todo.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.notifyMentions(user, todo, comment.Body, "")
+
+	return comment, nil
+}
+
+func (d *Domain) ListComments(todo *Todo) ([]*Comment, error) {
+	comments, err := d.DB.CommentRepo.ListByTodo(todo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,150 @@
+package domain
+
+import "time"
+
+// A TodoDependency says that TodoID can't be completed until BlockedByID is done
+type TodoDependency struct {
+	TodoID      int64 `json:"todoId" pg:",pk"`
+	BlockedByID int64 `json:"blockedById" pg:",pk"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DependencyEdge is a dependency together with the state of the blocking todo
+type DependencyEdge struct {
+	TodoID           int64 `json:"todoId"`
+	BlockedByID      int64 `json:"blockedById"`
+	BlockerCompleted bool  `json:"blockerCompleted"`
+}
+
+type AddDependencyPayload struct {
+	BlockedByID int64 `json:"blockedById"`
+}
+
+func (a *AddDependencyPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	if a.BlockedByID == 0 {
+		v.errors["blockedById"] = ErrIsRequired{field: "blockedById"}.Error()
+	}
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) AddDependency(todo *Todo, payload AddDependencyPayload, user *User) (*TodoDependency, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	blocker, err := d.DB.TodoRepo.GetByID(payload.BlockedByID)
+	if err != nil || !blocker.IsOwner(user) {
+		// we don't tell other users todos exist
+		return nil, ErrNoResult
+	}
+
+	if blocker.ID == todo.ID {
+		return nil, ErrDependencyCycle
+	}
+
+	// the graph is read again by the repo, with the todos locked
+	dependency, err := d.DB.DependencyRepo.Create(&TodoDependency{
+		TodoID:      todo.ID,
+		BlockedByID: blocker.ID,
+	}, user.ID, func(edges []*DependencyEdge) bool {
+		return createsCycle(edges, todo.ID, blocker.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dependency, nil
+}
+
+func (d *Domain) RemoveDependency(todo *Todo, blockedByID int64, user *User) error {
+	if err := mustOwn(todo, user); err != nil {
+		return err
+	}
+
+	return d.DB.DependencyRepo.Delete(&TodoDependency{
+		TodoID:      todo.ID,
+		BlockedByID: blockedByID,
+	})
+}
+
+func (d *Domain) ListDependencies(todo *Todo, user *User) ([]*DependencyEdge, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	edges, err := d.DB.DependencyRepo.ListByTodo(todo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+// createsCycle reports if adding "todoID is blocked by blockedByID" closes a cycle,
+// i.e if todoID already blocks blockedByID (directly or through other todos).
+func createsCycle(edges []*DependencyEdge, todoID, blockedByID int64) bool {
+	// adjacency list: todo -> the todos blocking it
+	blockers := make(map[int64][]int64)
+	for _, e := range edges {
+		blockers[e.TodoID] = append(blockers[e.TodoID], e.BlockedByID)
+	}
+
+	visited := make(map[int64]bool)
+	stack := []int64{blockedByID}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == todoID {
+			return true
+		}
+
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		stack = append(stack, blockers[current]...)
+	}
+
+	return false
+}
+
+// setBlocked fills Blocked and BlockedBy of the todos from the edges of the dependency graph
+func setBlocked(todos []*Todo, edges []*DependencyEdge) {
+	blockedBy := make(map[int64][]int64)
+	for _, e := range edges {
+		if !e.BlockerCompleted {
+			blockedBy[e.TodoID] = append(blockedBy[e.TodoID], e.BlockedByID)
+		}
+	}
+
+	for _, todo := range todos {
+		todo.BlockedBy = blockedBy[todo.ID]
+		if todo.BlockedBy == nil {
+			todo.BlockedBy = []int64{}
+		}
+		todo.Blocked = len(todo.BlockedBy) > 0
+	}
+}
+
+// ensureNotBlocked is checked before a todo moves to a done state
+func (d *Domain) ensureNotBlocked(todo *Todo) error {
+	edges, err := d.DB.DependencyRepo.ListByTodo(todo.ID)
+	if err != nil {
+		return err
+	}
+
+	setBlocked([]*Todo{todo}, edges)
+
+	if todo.Blocked {
+		return ErrTodoBlocked
+	}
+
+	return nil
+}
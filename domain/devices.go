@@ -0,0 +1,70 @@
+package domain
+
+import "strings"
+
+// the name comes from the user agent, which is up to the client
+const maxDeviceNameLength = 50
+
+// user agent fragments, checked in order since most browsers claim to be the others too
+var (
+	userAgentBrowsers = []struct{ fragment, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+
+	userAgentSystems = []struct{ fragment, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Macintosh", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceName is an approximate name of the device of a user agent, e.g "Firefox on Windows".
+// Other clients (curl, scripts, mobile apps) are named after their product
+func DeviceName(userAgent string) string {
+	var browser, system string
+
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.fragment) {
+			browser = b.name
+			break
+		}
+	}
+
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.fragment) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	// "curl/7.68.0" or "TodoApp/2.1 (iOS)"
+	if product := strings.Fields(userAgent); len(product) > 0 {
+		name := []rune(strings.SplitN(product[0], "/", 2)[0])
+		if len(name) > maxDeviceNameLength {
+			name = name[:maxDeviceNameLength]
+		}
+		return string(name)
+	}
+
+	return "Unknown device"
+}
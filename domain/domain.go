@@ -1,12 +1,129 @@
 package domain
 
+import (
+	"time"
+
+	"todo/oidc"
+	"todo/webauthn"
+)
+
 type UserRepo interface {
 	// As a refresher: Golang can return pointers to a var because is allocated in the heap
 	// https://www.geeksforgeeks.org/returning-pointer-from-a-function-in-go/
 	GetByEmail(email string) (*User, error)
 	GetByUsername(username string) (*User, error)
+	// GetByUsernameKey returns the user whose username can't be told apart from the one of the key
+	GetByUsernameKey(key string) (*User, error)
 	Create(user *User) (*User, error)
 	GetByID(id int64) (*User, error)
+	// Update only writes the profile (username, display name, timezone), the methods below write the rest
+	Update(user *User) (*User, error)
+	// UpdateEmail writes the email and when it was verified
+	UpdateEmail(user *User) (*User, error)
+	// MarkEmailVerified returns ErrNoResult when the email of the user changed in the meantime
+	MarkEmailVerified(user *User) (*User, error)
+	UpdateMFA(user *User) error
+	UpdateDisabled(user *User) (*User, error)
+	// UpdatePassword only writes the password
+	UpdatePassword(user *User) error
+	Search(filter UserFilter) ([]*User, error)
+	Delete(user *User) error
+	IncrementTokenGeneration(user *User) error
+	// SetMFALastStep only moves the step forward, false when another request used the step first
+	SetMFALastStep(user *User, step int64) (bool, error)
+}
+
+// Single use tokens sent to the users, looked up by hash. Consume only returns tokens that are unused and not expired
+type UserTokenRepo interface {
+	Create(token *UserToken) (*UserToken, error)
+	Get(purpose, hash string) (*UserToken, error)
+	Consume(purpose, hash string) (*UserToken, error)
+	// ConsumeForUser only consumes a token of the user
+	ConsumeForUser(userID int64, purpose, hash string) (*UserToken, error)
+	DeleteByUser(userID int64, purpose string) error
+}
+
+type PasskeyRepo interface {
+	Create(passkey *Passkey) (*Passkey, error)
+	GetByID(id int64) (*Passkey, error)
+	GetByCredentialID(credentialID []byte) (*Passkey, error)
+	ListByUser(userID int64) ([]*Passkey, error)
+	UpdateSignCount(passkey *Passkey) error
+	Delete(passkey *Passkey) error
+}
+
+// Identities of the OpenID providers, and the logins waiting for the callback of the provider
+type IdentityRepo interface {
+	Create(identity *UserIdentity) (*UserIdentity, error)
+	GetByID(id int64) (*UserIdentity, error)
+	GetByProviderSubject(provider, subject string) (*UserIdentity, error)
+	ListByUser(userID int64) ([]*UserIdentity, error)
+	Delete(identity *UserIdentity) error
+	CreateLoginState(state *OIDCLoginState) (*OIDCLoginState, error)
+	ConsumeLoginState(hash string) (*OIDCLoginState, error)
+	DeleteExpiredLoginStates(now time.Time) error
+}
+
+// Personal access tokens are looked up by the hash of the token the client sends
+type PersonalAccessTokenRepo interface {
+	Create(token *PersonalAccessToken) (*PersonalAccessToken, error)
+	GetByID(id int64) (*PersonalAccessToken, error)
+	GetByHash(hash string) (*PersonalAccessToken, error)
+	ListByUser(userID int64) ([]*PersonalAccessToken, error)
+	Update(token *PersonalAccessToken) (*PersonalAccessToken, error)
+	TouchLastUsed(token *PersonalAccessToken) error
+	Delete(token *PersonalAccessToken) error
+	DeleteByUser(userID int64) error
+}
+
+// Data exports and account deletions. ClaimDue marks the due jobs as running, so each runs once
+type AccountJobRepo interface {
+	Create(job *AccountJob) (*AccountJob, error)
+	GetByID(id int64) (*AccountJob, error)
+	GetPending(userID int64, kind string) (*AccountJob, error)
+	ListByUser(userID int64) ([]*AccountJob, error)
+	Update(job *AccountJob) (*AccountJob, error)
+	// Cancel cancels the pending job, false when it isn't pending anymore (e.g. it started running)
+	Cancel(job *AccountJob) (bool, error)
+	ClaimDue(now time.Time, limit int) ([]*AccountJob, error)
+	ListExpired(now time.Time) ([]*AccountJob, error)
+}
+
+// Hashes of the previous passwords of the users. DeleteOlder keeps the newest ones
+type PreviousPasswordRepo interface {
+	Create(password *PreviousPassword) (*PreviousPassword, error)
+	ListRecent(userID int64, limit int) ([]*PreviousPassword, error)
+	DeleteOlder(userID int64, keep int) error
+}
+
+// Sessions are the logins of the users, one per refresh token family
+type SessionRepo interface {
+	Create(session *Session) (*Session, error)
+	GetByID(id int64) (*Session, error)
+	GetByFamilyID(familyID string) (*Session, error)
+	// ListActiveByUser returns the sessions not revoked and seen since then, the last seen first
+	ListActiveByUser(userID int64, since time.Time) ([]*Session, error)
+	// KnownDevices are the device names of all the sessions of the user, revoked included
+	KnownDevices(userID int64) ([]string, error)
+	// Touch saves the last seen time and the address
+	Touch(session *Session) error
+	Revoke(session *Session) error
+	RevokeByUser(userID int64) error
+	DeleteInactive(before time.Time) error
+}
+
+type AuditLogRepo interface {
+	Create(entry *AuditLog) (*AuditLog, error)
+	List(filter AuditLogFilter) ([]*AuditLog, error)
+}
+
+// Refresh tokens are looked up by the hash of the token the client sends
+type RefreshTokenRepo interface {
+	Create(token *RefreshToken) (*RefreshToken, error)
+	GetByHash(hash string) (*RefreshToken, error)
+	MarkUsed(token *RefreshToken) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeByUser(userID int64) error
 }
 
 // We create a TODO repo for us:
@@ -16,9 +133,73 @@ type TodoRepo interface {
 	GetByID(id int64) (*Todo, error)
 	Update(todo *Todo) (*Todo, error)
 	Delete(todo *Todo) error
+	List(filter TodoFilter) ([]*Todo, error)
+	// ArchiveCompleted archives the completed todos of the users with an enabled archive rule
+	ArchiveCompleted(now time.Time) (int, error)
+	// ListSnoozed returns the todos still marked as snoozed whose snooze ended before now
+	ListSnoozed(now time.Time) ([]*Todo, error)
+	// Wake clears the snooze of the todo, false when it was changed in the meantime (snoozed again, woken up)
+	Wake(todoID int64, now time.Time) (bool, error)
+}
+
+type AssignmentRepo interface {
+	Create(assignment *Assignment) (*Assignment, error)
+	GetByID(id int64) (*Assignment, error)
+	Update(assignment *Assignment) (*Assignment, error)
+	ListPendingByAssignee(userID int64) ([]*Assignment, error)
+	// CancelPending cancels the assignment of the todo that is waiting for an answer, if any
+	CancelPending(todoID int64) error
+}
+
+type CommentRepo interface {
+	Create(comment *Comment) (*Comment, error)
+	ListByTodo(todoID int64) ([]*Comment, error)
+	// ListByUser returns the comments the user wrote, on any todo
+	ListByUser(userID int64) ([]*Comment, error)
+}
+
+type NotificationRepo interface {
+	Create(notification *Notification) (*Notification, error)
+	GetByID(id int64) (*Notification, error)
+	// ListByUser returns the unread notifications first, then the newest
+	ListByUser(userID int64) ([]*Notification, error)
+	CountUnread(userID int64) (int, error)
+	MarkRead(notification *Notification) (*Notification, error)
+	MarkAllRead(userID int64) error
+}
+
+type ArchiveRuleRepo interface {
+	GetByUser(userID int64) (*ArchiveRule, error)
+	Save(rule *ArchiveRule) (*ArchiveRule, error)
+}
+
+// Dependencies between todos ("blocked by"). The edges are returned with the state of the blocking todo
+type DependencyRepo interface {
+	Create(dependency *TodoDependency, userID int64, createsCycle func([]*DependencyEdge) bool) (*TodoDependency, error)
+	Delete(dependency *TodoDependency) error
+	ListByTodo(todoID int64) ([]*DependencyEdge, error)
+	ListByUser(userID int64) ([]*DependencyEdge, error)
+}
+
+type ProjectRepo interface {
+	Create(project *Project) (*Project, error)
+	GetByID(id int64) (*Project, error)
+	Update(project *Project) (*Project, error)
+	ListByUser(userID int64) ([]*Project, error)
+}
+
+// Time entries are the tracked time of a user on a todo. A running timer is an entry without StoppedAt
+type TimeEntryRepo interface {
+	Create(entry *TimeEntry) (*TimeEntry, error)
+	Update(entry *TimeEntry) (*TimeEntry, error)
+	GetRunning(userID int64) (*TimeEntry, error)
+	ListByTodo(todoID int64) ([]*TimeEntry, error)
+	ListByUser(filter TimeEntryFilter) ([]*TimeEntry, error)
+	Totals(filter TimeEntryFilter, groupBy TimeReportGroup) ([]*TimeReportRow, error)
 }
 
 // In order to avoid that other users can delete TODO id's from other users, we created the following interface
+// For todos the owners are the creator and the assignee, since both can edit it
 type HaveOwner interface {
 	IsOwner(user *User) bool
 }
@@ -28,10 +209,63 @@ type HaveOwner interface {
 // This will also make life easier and no cycle dependencies issue.
 
 type DB struct {
-	UserRepo UserRepo // DB has a UserRepo, which can be any time as long as the methods provided above are implemented
-	TodoRepo TodoRepo
+	UserRepo         UserRepo // DB has a UserRepo, which can be any time as long as the methods provided above are implemented
+	TodoRepo         TodoRepo
+	ProjectRepo      ProjectRepo
+	TimeEntryRepo    TimeEntryRepo
+	DependencyRepo   DependencyRepo
+	ArchiveRuleRepo  ArchiveRuleRepo
+	AssignmentRepo   AssignmentRepo
+	CommentRepo      CommentRepo
+	NotificationRepo NotificationRepo
+	RefreshTokenRepo RefreshTokenRepo
+	UserTokenRepo    UserTokenRepo
+	PasskeyRepo      PasskeyRepo
+	IdentityRepo     IdentityRepo
+
+	PersonalAccessTokenRepo PersonalAccessTokenRepo
+	AuditLogRepo            AuditLogRepo
+	AccountJobRepo          AccountJobRepo
+	PreviousPasswordRepo    PreviousPasswordRepo
+	SessionRepo             SessionRepo
+
+	Transactor Transactor // optional, the changes that go together are made one by one without it
+}
+
+// A Transactor runs fn with repos that share a transaction, committed when fn returns nil
+type Transactor interface {
+	RunInTransaction(fn func(db DB) error) error
 }
 type Domain struct {
 	DB DB // Same for this
 	// IMPORTANT: We do DB.UserRepo to create dependency injection.
+
+	Notifier       Notifier                  // optional, see notifier.go
+	Revocations    RevocationStore           // access tokens logged out before they expire
+	LoginAttempts  LoginAttemptStore         // failed logins, for the brute-force protection
+	PasswordPolicy *PasswordPolicy           // the default policy when nil
+	PasswordHasher PasswordHasher            // argon2id when nil, see password_hash.go
+	Keys           *KeyRing                  // signs and verifies the access tokens, see keys.go
+	Mailer         Mailer                    // sends the verification emails, see mailer.go
+	AppURL         string                    // base url of the web app, for the links in the emails
+	Secrets        *SecretBox                // encrypts the TOTP secrets, two-factor is disabled without it
+	WebAuthn       *webauthn.RelyingParty    // passkeys are disabled without it
+	OIDCProviders  map[string]*oidc.Provider // by name, for social login
+	Files          FileStore                 // keeps the data exports
+
+	// keep the case of the local part of the emails (Bob@), they're still matched ignoring case
+	KeepEmailLocalPartCase bool
+}
+
+// inTransaction runs fn with a copy of the domain whose repos share a transaction
+func (d *Domain) inTransaction(fn func(tx *Domain) error) error {
+	if d.DB.Transactor == nil {
+		return fn(d)
+	}
+
+	return d.DB.Transactor.RunInTransaction(func(db DB) error {
+		tx := *d
+		tx.DB = db
+		return fn(&tx)
+	})
 }
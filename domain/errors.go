@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
@@ -11,6 +12,47 @@ var (
 	ErrUserWithUsernameAlreadyExist = errors.New("user with username already exist")
 	ErrEmailBadFormat               = errors.New("Error: Email not valid")
 	ErrInvalidCredential            = errors.New("Error: Invalid credentials")
+	ErrTimezoneBadFormat            = errors.New("timezone not valid")
+	ErrInvalidToken                 = errors.New("Error: Invalid token")
+	ErrTokenExpired                 = errors.New("Error: Token expired")
+	ErrNoSigningKey                 = errors.New("a private key is required to sign tokens")
+	ErrUnknownSigningKey            = errors.New("unknown signing key")
+	ErrUnsupportedKey               = errors.New("unsupported key, use RSA, ECDSA P-256 or Ed25519")
+	ErrInvalidRefreshToken          = errors.New("Error: Invalid refresh token")
+	ErrForbidden                    = errors.New("forbidden")
+	ErrTimerAlreadyRunning          = errors.New("a timer is already running")
+	ErrNoRunningTimer               = errors.New("no running timer for this todo")
+	ErrWorkflowWithoutDoneState     = errors.New("at least one state must be a done state")
+	ErrDependencyCycle              = errors.New("dependency would create a cycle")
+	ErrTodoBlocked                  = errors.New("todo is blocked by other todos that are not done")
+	ErrSnoozePresetAndUntil         = errors.New("use either a preset or until")
+	ErrSnoozeInThePast              = errors.New("cannot snooze until a date in the past")
+	ErrCannotAssignToSelf           = errors.New("cannot assign a todo to yourself")
+	ErrAssignmentNotPending         = errors.New("assignment was already answered")
+	ErrInvalidReportGroup           = errors.New("groupBy must be one of todo, project or day")
+	ErrEmailAlreadyVerified         = errors.New("email is already verified")
+	ErrEmailNotVerified             = errors.New("email not verified")
+	ErrInvalidEncryptionKey         = errors.New("the encryption key must be 32 bytes encoded in base64")
+	ErrMFANotConfigured             = errors.New("two-factor authentication is not available")
+	ErrMFAAlreadyEnabled            = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled                = errors.New("two-factor authentication is not enabled")
+	ErrNoMFAEnrollment              = errors.New("start the two-factor enrollment first")
+	ErrInvalidMFACode               = errors.New("invalid code")
+	ErrPasskeysNotConfigured        = errors.New("passkeys are not available")
+	ErrPasskeyAlreadyRegistered     = errors.New("passkey is already registered")
+	ErrUnknownOIDCProvider          = errors.New("unknown login provider")
+	ErrOIDCEmailNotVerified         = errors.New("the provider did not verify the email")
+	ErrIdentityLinkedToOtherUser    = errors.New("this account of the provider is linked to another user")
+	ErrOIDCAccountNotLinked         = errors.New("an account with this email exists, log in with your password and link the provider from your account settings")
+	ErrAccountDisabled              = errors.New("account disabled")
+	ErrCannotDisableSelf            = errors.New("cannot disable your own account")
+	ErrAccountJobRunning            = errors.New("the job is already running")
+	ErrExportNotReady               = errors.New("the export is not ready")
+	ErrPasswordBreached             = errors.New("this password appeared in a data breach, choose another one")
+	ErrUnknownPasswordHash          = errors.New("unknown password hash")
+	ErrUnknownPasswordHasher        = errors.New("PASSWORD_HASHER must be argon2id or bcrypt")
+	ErrUsernameInvisibleCharacters  = errors.New("username cannot have invisible characters")
+	ErrUsernameMixedScripts         = errors.New("username cannot mix alphabets, e.g Latin and Cyrillic letters")
 )
 
 type ErrNotLongEnough struct {
@@ -22,6 +64,15 @@ func (e ErrNotLongEnough) Error() string {
 	return fmt.Sprintf("%v not long enough; %d characters is required", e.field, e.amount)
 }
 
+type ErrTooLong struct {
+	field  string
+	amount int
+}
+
+func (e ErrTooLong) Error() string {
+	return fmt.Sprintf("%v too long; %d characters at most", e.field, e.amount)
+}
+
 // we want this errnotlongmenough be a error type. to do so, because error is an interface
 // we must implement the function
 
@@ -40,3 +91,107 @@ type ErrMustMatch struct {
 func (e ErrMustMatch) Error() string {
 	return fmt.Sprintf("must match %v", e.field)
 }
+
+type ErrMustBeAfter struct {
+	field string
+}
+
+func (e ErrMustBeAfter) Error() string {
+	return fmt.Sprintf("must be after %v", e.field)
+}
+
+type ErrDuplicated struct {
+	value string
+}
+
+func (e ErrDuplicated) Error() string {
+	return fmt.Sprintf("%v is duplicated", e.value)
+}
+
+type ErrUnknownState struct {
+	state string
+}
+
+func (e ErrUnknownState) Error() string {
+	return fmt.Sprintf("unknown state %v", e.state)
+}
+
+type ErrInvalidTransition struct {
+	from string
+	to   string
+}
+
+func (e ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("cannot move from %v to %v", e.from, e.to)
+}
+
+type ErrNoTransition struct {
+	from string
+	done bool
+}
+
+func (e ErrNoTransition) Error() string {
+	if e.done {
+		return fmt.Sprintf("cannot complete a todo from %v", e.from)
+	}
+
+	return fmt.Sprintf("cannot reopen a todo from %v", e.from)
+}
+
+type ErrMustBeOneOf struct {
+	options []string
+}
+
+func (e ErrMustBeOneOf) Error() string {
+	return fmt.Sprintf("must be one of %v", strings.Join(e.options, ", "))
+}
+
+// ErrValidation carries the field errors of a payload that is validated inside the domain
+type ErrValidation struct {
+	Errors map[string]string
+}
+
+func (e ErrValidation) Error() string {
+	return "validation failed"
+}
+
+type ErrMustBeAtLeast struct {
+	amount int
+}
+
+func (e ErrMustBeAtLeast) Error() string {
+	return fmt.Sprintf("must be at least %d", e.amount)
+}
+
+type ErrPasswordTooWeak struct {
+	score int
+	min   int
+}
+
+func (e ErrPasswordTooWeak) Error() string {
+	return fmt.Sprintf("password is too easy to guess (strength %d of 4, %d is required), use a longer one", e.score, e.min)
+}
+
+type ErrPasswordTooSimilar struct {
+	field string
+}
+
+func (e ErrPasswordTooSimilar) Error() string {
+	return fmt.Sprintf("password is too similar to your %v", e.field)
+}
+
+type ErrPasswordReused struct {
+	amount int
+}
+
+func (e ErrPasswordReused) Error() string {
+	return fmt.Sprintf("cannot reuse one of your last %d passwords", e.amount)
+}
+
+type ErrInvalidPasswordPolicy struct {
+	env string
+}
+
+func (e ErrInvalidPasswordPolicy) Error() string {
+	return fmt.Sprintf("%v must be a positive number", e.env)
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// UserExport is everything tied to the user. Secrets (password, two-factor, token hashes) are left out
+type UserExport struct {
+	ExportedAt           time.Time              `json:"exportedAt"`
+	User                 *User                  `json:"user"`
+	Todos                []*Todo                `json:"todos"`
+	Comments             []*Comment             `json:"comments"`
+	Dependencies         []*DependencyEdge      `json:"dependencies"`
+	Projects             []*Project             `json:"projects"`
+	TimeEntries          []*TimeEntry           `json:"timeEntries"`
+	Notifications        []*Notification        `json:"notifications"`
+	Passkeys             []*Passkey             `json:"passkeys"`
+	Identities           []*UserIdentity        `json:"identities"`
+	PersonalAccessTokens []*PersonalAccessToken `json:"personalAccessTokens"`
+	Sessions             []*Session             `json:"sessions"`
+}
+
+// exportUserData returns the zip of the export. It has a data.json, todos have no attachments
+// yet; when they do, they go in an attachments folder next to it
+func (d *Domain) exportUserData(user *User) ([]byte, error) {
+	export := &UserExport{ExportedAt: time.Now(), User: user}
+	var err error
+
+	export.Todos, err = d.DB.TodoRepo.List(TodoFilter{
+		UserID:          user.ID,
+		View:            TodoViewAll,
+		IncludeArchived: true,
+		IncludeSnoozed:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the comments on the todos of the user, and the ones they wrote on the todos of others
+	seen := map[int64]bool{}
+	addComments := func(comments []*Comment) {
+		for _, comment := range comments {
+			if !seen[comment.ID] {
+				seen[comment.ID] = true
+				export.Comments = append(export.Comments, comment)
+			}
+		}
+	}
+
+	for _, todo := range export.Todos {
+		comments, err := d.DB.CommentRepo.ListByTodo(todo.ID)
+		if err != nil {
+			return nil, err
+		}
+		addComments(comments)
+	}
+
+	written, err := d.DB.CommentRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	addComments(written)
+
+	if export.Dependencies, err = d.DB.DependencyRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Projects, err = d.DB.ProjectRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.TimeEntries, err = d.DB.TimeEntryRepo.ListByUser(TimeEntryFilter{UserID: user.ID}); err != nil {
+		return nil, err
+	}
+
+	if export.Notifications, err = d.DB.NotificationRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Passkeys, err = d.DB.PasskeyRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Identities, err = d.DB.IdentityRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.PersonalAccessTokens, err = d.DB.PersonalAccessTokenRepo.ListByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Sessions, err = d.DB.SessionRepo.ListActiveByUser(user.ID, time.Now().Add(-RefreshTokenTTL)); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
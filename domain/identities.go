@@ -0,0 +1,332 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"todo/oidc"
+)
+
+// time to come back from the provider
+const OIDCLoginTTL = 10 * time.Minute
+
+// A UserIdentity links an account of an OpenID provider to the user
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (i *UserIdentity) IsOwner(user *User) bool {
+	return i.UserID == user.ID
+}
+
+// OIDCLoginState keeps what we need for the callback, the client only sees the state
+type OIDCLoginState struct {
+	ID           int64
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	UserID       *int64 // the user linking the provider, nil for logins
+	SessionID    *int64 // the session the link began from, nil for the tokens older than the sessions
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+// OIDCProviderNames are the providers users can log in with
+func (d *Domain) OIDCProviderNames() []string {
+	names := make([]string, 0, len(d.OIDCProviders))
+	for name := range d.OIDCProviders {
+		names = append(names, name)
+	}
+	return names
+}
+
+// BeginOIDCLogin returns where to send the user to log in with the provider
+func (d *Domain) BeginOIDCLogin(providerName string) (*OIDCAuthorization, error) {
+	return d.beginOIDC(providerName, &OIDCLoginState{})
+}
+
+// BeginOIDCLink returns where to send the user to link the provider to their account. The callback
+// must come from the same session, so a leaked state can't link another account of the provider
+func (d *Domain) BeginOIDCLink(providerName string, user *User, sessionID int64) (*OIDCAuthorization, error) {
+	state := &OIDCLoginState{UserID: &user.ID}
+	if sessionID != 0 {
+		state.SessionID = &sessionID
+	}
+
+	return d.beginOIDC(providerName, state)
+}
+
+func (d *Domain) beginOIDC(providerName string, loginState *OIDCLoginState) (*OIDCAuthorization, error) {
+	provider, ok := d.OIDCProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, challenge, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	loginState.StateHash = hashToken(state)
+	loginState.Provider = providerName
+	loginState.Nonce = nonce
+	loginState.CodeVerifier = verifier
+	loginState.ExpiresAt = time.Now().Add(OIDCLoginTTL)
+
+	url, err := provider.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.DB.IdentityRepo.CreateLoginState(loginState); err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthorization{AuthorizationURL: url}, nil
+}
+
+type OIDCCallbackPayload struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+func (o *OIDCCallbackPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("code", o.Code)
+	v.MustBeNotEmpty("state", o.State)
+
+	return v.IsValid(), v.errors
+}
+
+// FinishOIDCLogin returns the user of the provider identity. Unknown identities are linked to the
+// account with the same verified email, or get a new account. An account whose email was never
+// verified isn't linked: whoever registered it may not own the email, the owner must log in with
+// the password and link the provider from the account settings
+func (d *Domain) FinishOIDCLogin(providerName string, payload OIDCCallbackPayload) (*User, error) {
+	state, claims, err := d.exchangeOIDC(providerName, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// the states of the links are only finished by FinishOIDCLink
+	if state.UserID != nil {
+		return nil, ErrInvalidToken
+	}
+
+	identity, err := d.DB.IdentityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err != nil && !errors.Is(err, ErrNoResult) {
+		return nil, err
+	}
+
+	if identity != nil {
+		return d.DB.UserRepo.GetByID(identity.UserID)
+	}
+
+	// the email is only trusted when the provider verified it
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := d.DB.UserRepo.GetByEmail(d.normalizeEmail(claims.Email))
+	if err != nil {
+		if !errors.Is(err, ErrNoResult) {
+			return nil, err
+		}
+
+		user, err = d.createOIDCUser(claims)
+		if err != nil {
+			return nil, err
+		}
+	} else if !user.IsEmailVerified() {
+		return nil, ErrOIDCAccountNotLinked
+	}
+
+	if _, err := d.linkIdentity(user, providerName, claims); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// FinishOIDCLink links the provider identity to the user that began the link, from the same session
+func (d *Domain) FinishOIDCLink(providerName string, payload OIDCCallbackPayload, user *User, sessionID int64) (*UserIdentity, error) {
+	state, claims, err := d.exchangeOIDC(providerName, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.UserID == nil || *state.UserID != user.ID {
+		return nil, ErrInvalidToken
+	}
+
+	if state.SessionID != nil && *state.SessionID != sessionID {
+		return nil, ErrInvalidToken
+	}
+
+	identity, err := d.DB.IdentityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err != nil && !errors.Is(err, ErrNoResult) {
+		return nil, err
+	}
+
+	if identity != nil {
+		if identity.UserID != user.ID {
+			return nil, ErrIdentityLinkedToOtherUser
+		}
+		return identity, nil
+	}
+
+	return d.linkIdentity(user, providerName, claims)
+}
+
+// exchangeOIDC consumes the state of the callback and returns the claims of the provider
+func (d *Domain) exchangeOIDC(providerName string, payload OIDCCallbackPayload) (*OIDCLoginState, *oidc.Claims, error) {
+	provider, ok := d.OIDCProviders[providerName]
+	if !ok {
+		return nil, nil, ErrUnknownOIDCProvider
+	}
+
+	state, err := d.DB.IdentityRepo.ConsumeLoginState(hashToken(payload.State))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	if state.Provider != providerName {
+		return nil, nil, ErrInvalidToken
+	}
+
+	claims, err := provider.Exchange(context.Background(), payload.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return state, claims, nil
+}
+
+func (d *Domain) linkIdentity(user *User, provider string, claims *oidc.Claims) (*UserIdentity, error) {
+	return d.DB.IdentityRepo.Create(&UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// createOIDCUser registers the user of the provider. The password is random, it can be set with a reset
+func (d *Domain) createOIDCUser(claims *oidc.Claims) (*User, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.Split(claims.Email, "@")[0]
+	}
+
+	base = usernameUnsafe.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	username, err := d.availableUsername(base)
+	if err != nil {
+		return nil, err
+	}
+
+	random, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := d.setPassword(random)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return d.DB.UserRepo.Create(&User{
+		Username:        username,
+		Email:           d.normalizeEmail(claims.Email),
+		Password:        *password,
+		EmailVerifiedAt: &now,
+	})
+}
+
+func (d *Domain) availableUsername(base string) (string, error) {
+	username := base
+
+	for i := 0; i < 5; i++ {
+		if _, err := d.DB.UserRepo.GetByUsernameKey(UsernameKey(username)); errors.Is(err, ErrNoResult) {
+			return username, nil
+		}
+
+		suffix, err := randomToken(3)
+		if err != nil {
+			return "", err
+		}
+
+		username = fmt.Sprintf("%s-%s", base, suffix)
+	}
+
+	return "", ErrUserWithUsernameAlreadyExist
+}
+
+func (d *Domain) ListIdentities(user *User) ([]*UserIdentity, error) {
+	return d.DB.IdentityRepo.ListByUser(user.ID)
+}
+
+func (d *Domain) GetIdentityByID(id int64) (*UserIdentity, error) {
+	return d.DB.IdentityRepo.GetByID(id)
+}
+
+// UnlinkIdentity removes the provider from the account, the password and other providers keep working
+func (d *Domain) UnlinkIdentity(identity *UserIdentity, user *User) error {
+	if err := mustOwn(identity, user); err != nil {
+		return err
+	}
+
+	return d.DB.IdentityRepo.Delete(identity)
+}
+
+// DeleteExpiredOIDCStates runs as a background job
+func (d *Domain) DeleteExpiredOIDCStates(now time.Time) error {
+	return d.DB.IdentityRepo.DeleteExpiredLoginStates(now)
+}
+
+type LinkIdentityPayload struct {
+	Provider string `json:"provider"`
+}
+
+func (l *LinkIdentityPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("provider", l.Provider)
+
+	return v.IsValid(), v.errors
+}
@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"todo/domain"
+)
+
+func TestIdentityKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     func(string) string
+		a, b    string
+		collide bool
+	}{
+		{name: "username case", key: domain.UsernameKey, a: "Bob", b: "bob", collide: true},
+		{name: "username fullwidth", key: domain.UsernameKey, a: "Ｂｏｂ", b: "bob", collide: true},
+		{name: "username cyrillic", key: domain.UsernameKey, a: "рор", b: "pop", collide: true},
+		{name: "username case folding", key: domain.UsernameKey, a: "straße", b: "STRASSE", collide: true},
+		{name: "username digit", key: domain.UsernameKey, a: "bob1", b: "bobl", collide: true},
+		{name: "username rn", key: domain.UsernameKey, a: "bourne", b: "boume", collide: true},
+		{name: "username spaces", key: domain.UsernameKey, a: " bob ", b: "bob", collide: true},
+		{name: "different usernames", key: domain.UsernameKey, a: "bob", b: "rob"},
+		{name: "email case", key: domain.EmailKey, a: "Bob@Example.com", b: "bob@example.com", collide: true},
+		{name: "email fullwidth", key: domain.EmailKey, a: "ｂｏｂ@example.com", b: "bob@example.com", collide: true},
+		{name: "different emails", key: domain.EmailKey, a: "bob@example.com", b: "bob@example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.key(tt.a), tt.key(tt.b)
+			if (a == b) != tt.collide {
+				t.Errorf("keys of %q and %q are %q and %q, collide %v", tt.a, tt.b, a, b, tt.collide)
+			}
+		})
+	}
+}
+
+func TestRegisterUsernameKeyCollision(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     error
+	}{
+		{name: "same username", username: "bob", want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "other case", username: "BOB", want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "digit for a letter", username: "b0b", want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "other username", username: "rob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDomain(t)
+			d.DB.UserRepo.(*memoryUserRepo).add(&domain.User{Username: "bob", Email: "bob@example.com"})
+
+			_, err := d.Register(domain.RegisterPayload{
+				Username: tt.username,
+				Email:    "someone@example.com",
+				Password: "tangerine-orbit-57-quietly",
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// A Job is background work that runs periodically (e.g archiving completed todos)
+type Job func(now time.Time) error
+
+// RunJob calls job every interval until ctx is done. Errors are logged and the job keeps running
+func RunJob(ctx context.Context, name string, interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(time.Now()); err != nil {
+			log.Printf("job %s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package domain
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Access tokens are signed with an asymmetric key, so other services can verify them with the public keys
+// published in /.well-known/jwks.json without sharing any secret.
+//
+// To rotate a key: add the new key to JWT_VERIFICATION_KEYS everywhere, then make it the JWT_SIGNING_KEY
+// and keep the old one in JWT_VERIFICATION_KEYS until the tokens it signed expired (AccessTokenTTL).
+
+const defaultLeeway = 30 * time.Second
+
+// A SigningKey signs or verifies tokens. Keys only kept to verify tokens during a rotation have no PrivateKey
+type SigningKey struct {
+	ID         string // kid header
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+type KeyRing struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration // clock skew allowed when checking exp, nbf and iat
+
+	signing *SigningKey
+	keys    map[string]*SigningKey
+}
+
+// NewKeyRing signs with the signing key and accepts tokens of it and of any verification key
+func NewKeyRing(issuer, audience string, leeway time.Duration, signing *SigningKey, verification ...*SigningKey) (*KeyRing, error) {
+	if signing == nil || signing.PrivateKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	k := &KeyRing{
+		Issuer:   issuer,
+		Audience: audience,
+		Leeway:   leeway,
+		signing:  signing,
+		keys:     map[string]*SigningKey{signing.ID: signing},
+	}
+
+	for _, key := range verification {
+		k.keys[key.ID] = key
+	}
+
+	return k, nil
+}
+
+// Sign adds the registered claims (iss, aud, iat, nbf) and signs with the current key
+func (k *KeyRing) Sign(claims jwt.MapClaims) (string, error) {
+	now := time.Now().Unix()
+	claims["iss"] = k.Issuer
+	claims["aud"] = k.Audience
+	claims["iat"] = now
+	claims["nbf"] = now
+
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.PrivateKey)
+}
+
+// Parse verifies the signature with the key of the kid header and validates the claims.
+// Only the algorithms of our keys are allowed, and each key only with its own algorithm
+func (k *KeyRing) Parse(tokenString string) (*jwt.Token, error) {
+	parser := &jwt.Parser{
+		ValidMethods:         k.algorithms(),
+		SkipClaimsValidation: true, // jwt-go has no leeway, we validate them below
+	}
+
+	token, err := parser.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+
+		return key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.validateClaims(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (k *KeyRing) algorithms() []string {
+	var algs []string
+	seen := make(map[string]bool)
+
+	for _, key := range k.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+
+	return algs
+}
+
+func (k *KeyRing) validateClaims(token *jwt.Token) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	now := time.Now()
+	leeway := int64(k.Leeway / time.Second)
+
+	// exp is required, the others are checked when present
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.Unix()-leeway > int64(exp) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix()+leeway < int64(nbf) {
+		return ErrInvalidToken
+	}
+
+	if iat, ok := claims["iat"].(float64); ok && now.Unix()+leeway < int64(iat) {
+		return ErrInvalidToken
+	}
+
+	if iss, _ := claims["iss"].(string); iss != k.Issuer {
+		return ErrInvalidToken
+	}
+
+	if !claims.VerifyAudience(k.Audience, true) {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// JWK is the public part of a key as published in the JWKS (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every key we accept, so verifiers keep working during a rotation
+func (k *KeyRing) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range k.keys {
+		jwk, err := publicJWK(key.PublicKey)
+		if err != nil {
+			continue
+		}
+
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func publicJWK(key crypto.PublicKey) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JWK{Kty: "EC", Crv: key.Curve.Params().Name, X: b64(padded(key.X, size)), Y: b64(padded(key.Y, size))}, nil
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: b64(key)}, nil
+	}
+
+	return JWK{}, ErrUnsupportedKey
+}
+
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// thumbprint is the RFC 7638 thumbprint of the key, which we use as kid
+func thumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	// the members must be in lexicographic order, without spaces
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// NewSigningKey wraps a private or public key, picking the algorithm from the key type:
+// RSA -> RS256, ECDSA P-256 -> ES256, Ed25519 -> EdDSA
+func NewSigningKey(key interface{}) (*SigningKey, error) {
+	k := &SigningKey{}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.PrivateKey, k.PublicKey = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.PublicKey = jwt.SigningMethodRS256, key
+	case *ecdsa.PrivateKey:
+		k.Method, k.PrivateKey, k.PublicKey = jwt.SigningMethodES256, key, &key.PublicKey
+	case *ecdsa.PublicKey:
+		k.Method, k.PublicKey = jwt.SigningMethodES256, key
+	case ed25519.PrivateKey:
+		k.Method, k.PrivateKey, k.PublicKey = SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.PublicKey = SigningMethodEdDSA, key
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	if ec, ok := k.PublicKey.(*ecdsa.PublicKey); ok && ec.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+
+	id, err := thumbprint(k.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	k.ID = id
+
+	return k, nil
+}
+
+// ParseSigningKeyPEM reads a PKCS#8 private key, a SEC 1 EC or PKCS#1 RSA private key, or a PKIX public key
+func ParseSigningKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	var key interface{}
+	var err error
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKey(key)
+}
+
+// KeyRingFromEnv builds the key ring from:
+//
+//	JWT_SIGNING_KEY        PEM file of the private key that signs new tokens
+//	JWT_VERIFICATION_KEYS  comma separated PEM files of older keys still accepted
+//	JWT_ISSUER, JWT_AUDIENCE and JWT_LEEWAY (e.g "30s")
+//
+// Without JWT_SIGNING_KEY a temporary ES256 key is generated, so tokens don't survive a restart.
+func KeyRingFromEnv() (*KeyRing, error) {
+	issuer := envOr("JWT_ISSUER", "todo-api")
+	audience := envOr("JWT_AUDIENCE", "todo-api")
+
+	leeway := defaultLeeway
+	if v := os.Getenv("JWT_LEEWAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		leeway = d
+	}
+
+	var signing *SigningKey
+
+	if path := os.Getenv("JWT_SIGNING_KEY"); path != "" {
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		signing = key
+	} else {
+		log.Println("JWT_SIGNING_KEY is not set, signing tokens with a temporary key")
+
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		signing, err = NewSigningKey(private)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var verification []*SigningKey
+
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+
+	return NewKeyRing(issuer, audience, leeway, signing, verification...)
+}
+
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParseSigningKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return fallback
+}
@@ -0,0 +1,254 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Failed logins are counted per account and per client address. Past the free attempts each new
+// failure doubles the wait before the next attempt, up to loginLockout
+const (
+	loginFreeAttemptsPerAccount = 5
+	loginFreeAttemptsPerIP      = 20
+	loginBaseDelay              = time.Second
+	loginLockout                = 15 * time.Minute
+
+	// the failures are forgotten when there was none for this long
+	LoginAttemptsWindow = 24 * time.Hour
+)
+
+// LoginAttempts are the recent failed logins of an account or of an address
+type LoginAttempts struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// retryAfter is how long the next attempt must wait, zero when it can go now
+func (a *LoginAttempts) retryAfter(now time.Time, freeAttempts int) time.Duration {
+	if a == nil || a.Failures < freeAttempts {
+		return 0
+	}
+
+	delay := loginLockout
+	if exp := a.Failures - freeAttempts; exp < 20 {
+		if backoff := loginBaseDelay << uint(exp); backoff < loginLockout {
+			delay = backoff
+		}
+	}
+
+	wait := a.LastFailure.Add(delay).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// A LoginAttemptStore counts the failed logins by key. Get returns nil when there's none in the window
+type LoginAttemptStore interface {
+	Get(key string) (*LoginAttempts, error)
+	Fail(key string, now time.Time) (*LoginAttempts, error)
+	Reset(key string) error
+}
+
+// The key of the account is the email as typed, so unknown emails are throttled like the others
+// and the lockout doesn't tell whether the account exists
+func loginAccountKey(email string) string {
+	return "account:" + EmailKey(email)
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// the codes of the second factor are counted per user, whatever pending token they come with
+func mfaKey(userID int64) string {
+	return fmt.Sprintf("mfa:%d", userID)
+}
+
+// ErrTooManyLoginAttempts is returned before checking the password, RetryAfter is the wait
+type ErrTooManyLoginAttempts struct {
+	RetryAfter time.Duration
+}
+
+func (e ErrTooManyLoginAttempts) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", e.RetrySeconds())
+}
+
+// RetrySeconds rounds the wait up, for the Retry-After header
+func (e ErrTooManyLoginAttempts) RetrySeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// checkLoginThrottle fails when the account or the address must wait before trying again
+func (d *Domain) checkLoginThrottle(email, ip string, now time.Time) error {
+	if d.LoginAttempts == nil {
+		return nil
+	}
+
+	account, err := d.LoginAttempts.Get(loginAccountKey(email))
+	if err != nil {
+		return err
+	}
+
+	wait := account.retryAfter(now, loginFreeAttemptsPerAccount)
+
+	if ip != "" {
+		address, err := d.LoginAttempts.Get(loginIPKey(ip))
+		if err != nil {
+			return err
+		}
+
+		if ipWait := address.retryAfter(now, loginFreeAttemptsPerIP); ipWait > wait {
+			wait = ipWait
+		}
+	}
+
+	if wait > 0 {
+		return ErrTooManyLoginAttempts{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+func (d *Domain) recordLoginFailure(email, ip string, now time.Time) error {
+	if d.LoginAttempts == nil {
+		return nil
+	}
+
+	if _, err := d.LoginAttempts.Fail(loginAccountKey(email), now); err != nil {
+		return err
+	}
+
+	if ip != "" {
+		if _, err := d.LoginAttempts.Fail(loginIPKey(ip), now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordLoginSuccess only resets the account, an attacker with an account of their own
+// must not be able to reset the counter of their address
+func (d *Domain) recordLoginSuccess(email string) error {
+	if d.LoginAttempts == nil {
+		return nil
+	}
+
+	return d.LoginAttempts.Reset(loginAccountKey(email))
+}
+
+// checkMFAThrottle fails when the user must wait before trying another code
+func (d *Domain) checkMFAThrottle(userID int64, now time.Time) error {
+	if d.LoginAttempts == nil {
+		return nil
+	}
+
+	attempts, err := d.LoginAttempts.Get(mfaKey(userID))
+	if err != nil {
+		return err
+	}
+
+	if wait := attempts.retryAfter(now, loginFreeAttemptsPerAccount); wait > 0 {
+		return ErrTooManyLoginAttempts{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// recordMFAFailure returns the failures of the user in the window
+func (d *Domain) recordMFAFailure(userID int64, now time.Time) (int, error) {
+	if d.LoginAttempts == nil {
+		return 0, nil
+	}
+
+	attempts, err := d.LoginAttempts.Fail(mfaKey(userID), now)
+	if err != nil {
+		return 0, err
+	}
+
+	return attempts.Failures, nil
+}
+
+func (d *Domain) recordMFASuccess(userID int64) error {
+	if d.LoginAttempts == nil {
+		return nil
+	}
+
+	return d.LoginAttempts.Reset(mfaKey(userID))
+}
+
+// MemoryLoginAttemptStore keeps the counters in memory.
+// It's only valid for a single instance, use the postgres store otherwise
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]*LoginAttempts
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts:  make(map[string]*LoginAttempts),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryLoginAttemptStore) Get(key string) (*LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	attempts, ok := m.attempts[key]
+	if !ok || now.Sub(attempts.LastFailure) >= LoginAttemptsWindow {
+		return nil, nil
+	}
+
+	result := *attempts
+	return &result, nil
+}
+
+func (m *MemoryLoginAttemptStore) Fail(key string, now time.Time) (*LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	attempts, ok := m.attempts[key]
+	if !ok || now.Sub(attempts.LastFailure) >= LoginAttemptsWindow {
+		attempts = &LoginAttempts{}
+		m.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+
+	result := *attempts
+	return &result, nil
+}
+
+func (m *MemoryLoginAttemptStore) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// sweep forgets the counters out of the window, at most once per revocationSweepInterval. mu must be held
+func (m *MemoryLoginAttemptStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < revocationSweepInterval {
+		return
+	}
+
+	for key, attempts := range m.attempts {
+		if now.Sub(attempts.LastFailure) >= LoginAttemptsWindow {
+			delete(m.attempts, key)
+		}
+	}
+
+	m.lastSweep = now
+}
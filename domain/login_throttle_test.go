@@ -0,0 +1,63 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"todo/domain"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	const password = "tangerine-orbit-57"
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "known account", email: "bob@example.com"},
+		{name: "unknown account", email: "nobody@example.com"},
+		{name: "email typed differently", email: "BOB@example.com"},
+	}
+
+	// the answers of every case, they must not tell the accounts apart
+	var answers []string
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDomain(t)
+			d.DB.UserRepo.(*memoryUserRepo).add(&domain.User{
+				Username: "bob",
+				Email:    "bob@example.com",
+				Password: hashPassword(t, password),
+			})
+
+			var answer string
+			for i := 1; i <= 5; i++ {
+				_, err := d.Login(domain.LoginPayload{Email: tt.email, Password: "wrong password"}, "192.0.2.1")
+				if !errors.Is(err, domain.ErrInvalidCredential) {
+					t.Fatalf("attempt %d: got %v, want %v", i, err, domain.ErrInvalidCredential)
+				}
+				answer += err.Error() + "\n"
+			}
+
+			// locked out, even with the right password and from another address
+			_, err := d.Login(domain.LoginPayload{Email: tt.email, Password: password}, "198.51.100.7")
+
+			var throttled domain.ErrTooManyLoginAttempts
+			if !errors.As(err, &throttled) {
+				t.Fatalf("got %v, want ErrTooManyLoginAttempts", err)
+			}
+			if throttled.RetrySeconds() != 1 {
+				t.Errorf("retry after %d seconds, want 1", throttled.RetrySeconds())
+			}
+
+			answers = append(answers, answer+err.Error())
+		})
+	}
+
+	for i := range answers {
+		if answers[i] != answers[0] {
+			t.Errorf("%s answered %q, %s answered %q", tests[i].name, answers[i], tests[0].name, answers[0])
+		}
+	}
+}
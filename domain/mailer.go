@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// A Mailer sends emails to the users. See the mailer package for the implementations
+type Mailer interface {
+	Send(message Message) error
+}
+
+// sendMail doesn't fail the action that sends the email, the user can always ask for it again
+func (d *Domain) sendMail(message Message) {
+	if d.Mailer == nil {
+		log.Printf("no mailer configured, cannot send %q to %s", message.Subject, message.To)
+		return
+	}
+
+	if err := d.Mailer.Send(message); err != nil {
+		log.Printf("cannot send %q to %s: %v", message.Subject, message.To, err)
+	}
+}
+
+// appLink builds a link to the web app, e.g appLink("/verify-email", token)
+func (d *Domain) appLink(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", d.AppURL, path, token)
+}
@@ -0,0 +1,380 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// the "typ" claim tells access tokens apart from the other tokens we sign
+	TokenTypeAccess     = "access"
+	TokenTypeMFAPending = "mfa_pending"
+
+	// time to enter the code after the password
+	MFAPendingTTL = 5 * time.Minute
+
+	totpIssuer         = "Todo"
+	recoveryCodesCount = 10
+)
+
+func (u *User) IsMFAEnabled() bool {
+	return u.MFAEnabledAt != nil
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// URI, also available as a QR code
+}
+
+// BeginTOTPEnrollment creates a new secret. It's not used for login until confirmed with a code
+func (d *Domain) BeginTOTPEnrollment(user *User) (*TOTPEnrollment, error) {
+	if d.Secrets == nil {
+		return nil, ErrMFANotConfigured
+	}
+
+	if user.IsMFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := d.Secrets.Seal([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFASecret = sealed
+	user.UpdatedAt = time.Now()
+
+	if err := d.DB.UserRepo.UpdateMFA(user); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// TOTPEnrollmentURI returns the URI of the enrollment waiting for confirmation
+func (d *Domain) TOTPEnrollmentURI(user *User) (string, error) {
+	if user.IsMFAEnabled() {
+		return "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := d.totpSecret(user)
+	if err != nil {
+		return "", err
+	}
+
+	return totpURI(totpIssuer, user.Email, secret), nil
+}
+
+type MFACodePayload struct {
+	Code string `json:"code"`
+}
+
+func (m *MFACodePayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("code", m.Code)
+
+	return v.IsValid(), v.errors
+}
+
+// ConfirmTOTPEnrollment enables the second factor and returns the recovery codes, they are only shown here
+func (d *Domain) ConfirmTOTPEnrollment(user *User, payload MFACodePayload) ([]string, error) {
+	if user.IsMFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := d.verifyTOTP(user, payload.Code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.MFAEnabledAt = &now
+	user.UpdatedAt = now
+
+	if err := d.DB.UserRepo.UpdateMFA(user); err != nil {
+		return nil, err
+	}
+
+	return d.newRecoveryCodes(user)
+}
+
+// MFAPayload takes either a code of the authenticator or a recovery code
+type MFAPayload struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (m *MFAPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	if m.RecoveryCode == "" {
+		v.MustBeNotEmpty("code", m.Code)
+	}
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) DisableTOTP(user *User, payload MFAPayload) error {
+	if !user.IsMFAEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	if err := d.verifySecondFactor(user, payload); err != nil {
+		return err
+	}
+
+	user.MFASecret = ""
+	user.MFAEnabledAt = nil
+	user.UpdatedAt = time.Now()
+
+	if err := d.DB.UserRepo.UpdateMFA(user); err != nil {
+		return err
+	}
+
+	return d.DB.UserTokenRepo.DeleteByUser(user.ID, TokenPurposeMFARecovery)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, the old ones stop working
+func (d *Domain) RegenerateRecoveryCodes(user *User, payload MFACodePayload) ([]string, error) {
+	if !user.IsMFAEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := d.verifyTOTP(user, payload.Code); err != nil {
+		return nil, err
+	}
+
+	return d.newRecoveryCodes(user)
+}
+
+// MFAChallenge is the answer of the login when the user has a second factor
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfaRequired"`
+	MFAToken    string    `json:"mfaToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// NewMFAChallenge signs the short lived token that proves the password was right
+func (d *Domain) NewMFAChallenge(user *User) (*MFAChallenge, error) {
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	expiresAt := time.Now().Add(MFAPendingTTL)
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := d.Keys.Sign(jwt.MapClaims{
+		"id":  user.ID,
+		"exp": expiresAt.Unix(),
+		"jti": jti,
+		"typ": TokenTypeMFAPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+type MFALoginPayload struct {
+	MFAToken string `json:"mfaToken"`
+	MFAPayload
+}
+
+func (m *MFALoginPayload) IsValid() (bool, map[string]string) {
+	_, errs := m.MFAPayload.IsValid()
+
+	v := &Validator{errors: errs}
+	v.MustBeNotEmpty("mfaToken", m.MFAToken)
+
+	return v.IsValid(), v.errors
+}
+
+// LoginMFA exchanges the mfa pending token and a code for the user. The pending token works once.
+// Wrong codes are throttled like the passwords, and past the free attempts each one also revokes
+// the pending token, so guessing the code needs the password again every time
+func (d *Domain) LoginMFA(payload MFALoginPayload) (*User, error) {
+	now := time.Now()
+
+	token, err := d.Keys.Parse(payload.MFAToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != TokenTypeMFAPending {
+		return nil, ErrInvalidToken
+	}
+
+	id, _ := claims["id"].(float64)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+
+	if jti == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if d.Revocations != nil {
+		revoked, err := d.Revocations.IsRevoked(jti)
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	user, err := d.DB.UserRepo.GetByID(int64(id))
+	if err != nil || !user.IsMFAEnabled() {
+		return nil, ErrInvalidToken
+	}
+
+	if err := d.checkMFAThrottle(user.ID, now); err != nil {
+		return nil, err
+	}
+
+	if err := d.verifySecondFactor(user, payload.MFAPayload); err != nil {
+		failures, recordErr := d.recordMFAFailure(user.ID, now)
+		if recordErr != nil {
+			log.Printf("cannot record the failed code: %v", recordErr)
+		}
+
+		if failures >= loginFreeAttemptsPerAccount && d.Revocations != nil {
+			if err := d.Revocations.Revoke(jti, time.Unix(int64(exp), 0)); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, err
+	}
+
+	if err := d.recordMFASuccess(user.ID); err != nil {
+		log.Printf("cannot reset the failed codes: %v", err)
+	}
+
+	if d.Revocations != nil {
+		if err := d.Revocations.Revoke(jti, time.Unix(int64(exp), 0)); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (d *Domain) totpSecret(user *User) (string, error) {
+	if d.Secrets == nil {
+		return "", ErrMFANotConfigured
+	}
+
+	if user.MFASecret == "" {
+		return "", ErrNoMFAEnrollment
+	}
+
+	secret, err := d.Secrets.Open(user.MFASecret)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// verifyTOTP checks the code and records its step, so it can't be replayed
+func (d *Domain) verifyTOTP(user *User, code string) error {
+	secret, err := d.totpSecret(user)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(secret, strings.TrimSpace(code), time.Now(), user.MFALastStep)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	// two requests with the same code race here, only one of them moves the step forward
+	ok, err = d.DB.UserRepo.SetMFALastStep(user, step)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (d *Domain) verifySecondFactor(user *User, payload MFAPayload) error {
+	if payload.RecoveryCode == "" {
+		return d.verifyTOTP(user, payload.Code)
+	}
+
+	// the code of another user must not be used up
+	_, err := d.DB.UserTokenRepo.ConsumeForUser(user.ID, TokenPurposeMFARecovery, hashToken(normalizeRecoveryCode(payload.RecoveryCode)))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	return nil
+}
+
+// newRecoveryCodes replaces the codes of the user. They are stored hashed like the other user tokens
+func (d *Domain) newRecoveryCodes(user *User) ([]string, error) {
+	if err := d.DB.UserTokenRepo.DeleteByUser(user.ID, TokenPurposeMFARecovery); err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b)) // 16 characters
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+
+		_, err := d.DB.UserTokenRepo.Create(&UserToken{
+			Purpose:   TokenPurposeMFARecovery,
+			TokenHash: hashToken(normalizeRecoveryCode(codes[i])),
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
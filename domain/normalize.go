@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Emails and usernames are matched ignoring case (the unique indexes of the users are on LOWER),
+// the functions below give the form we store and the keys we compare
+
+// NormalizeEmail is the email we store: NFKC and the domain in lowercase. The local part is
+// lowercased too unless keepLocalPartCase, for the mail servers that tell Bob@ from bob@
+func NormalizeEmail(email string, keepLocalPartCase bool) string {
+	email = norm.NFKC.String(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if !keepLocalPartCase {
+		local = strings.ToLower(local)
+	}
+
+	return local + "@" + domain
+}
+
+// EmailKey is the same for the emails of one account
+func EmailKey(email string) string {
+	return cases.Fold().String(NormalizeEmail(email, false))
+}
+
+func (d *Domain) normalizeEmail(email string) string {
+	return NormalizeEmail(email, d.KeepEmailLocalPartCase)
+}
+
+// NormalizeUsername is the username we store, NFKC so the same characters are always written the same
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+// characters that look like the Latin ones once lowercased, a subset of the confusables of Unicode (UTS #39)
+var confusables = strings.NewReplacer(
+	// Cyrillic
+	"а", "a", "с", "c", "ԁ", "d", "е", "e", "һ", "h", "і", "i", "ј", "j", "к", "k", "о", "o",
+	"р", "p", "ԛ", "q", "ѕ", "s", "у", "y", "х", "x", "ԝ", "w", "ү", "y",
+	// Greek
+	"α", "a", "ι", "i", "κ", "k", "ν", "v", "ο", "o", "ρ", "p", "υ", "u", "χ", "x",
+	// Latin and digits
+	"ı", "i", "ɡ", "g", "0", "o", "1", "l", "|", "l", "rn", "m", "vv", "w",
+)
+
+// UsernameKey is the same for the usernames people can't tell apart: NFKC, case folding and the
+// confusable characters replaced by the Latin ones they look like. bob, Bob and bоb (Cyrillic о) share it
+func UsernameKey(username string) string {
+	return confusables.Replace(cases.Fold().String(NormalizeUsername(username)))
+}
+
+// scripts a username can mix, like Japanese does (the others must use a single script)
+var compatibleScripts = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Hangul"},
+	{"Latin", "Han", "Bopomofo"},
+}
+
+// mustBeSafeUsername rejects the usernames that can pass for others: invisible characters and
+// scripts mixed inside a name (e.g a Cyrillic а in a Latin name)
+func (v *Validator) mustBeSafeUsername(field, username string) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	scripts := map[string]bool{}
+	for _, r := range norm.NFKC.String(username) {
+		if unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp) {
+			v.errors[field] = ErrUsernameInvisibleCharacters.Error()
+			return false
+		}
+
+		for name, table := range unicode.Scripts {
+			if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+				scripts[name] = true
+				break
+			}
+		}
+	}
+
+	if len(scripts) <= 1 {
+		return true
+	}
+
+	for _, compatible := range compatibleScripts {
+		if subsetOf(scripts, compatible) {
+			return true
+		}
+	}
+
+	v.errors[field] = ErrUsernameMixedScripts.Error()
+	return false
+}
+
+func subsetOf(set map[string]bool, values []string) bool {
+	for name := range set {
+		if !containsString(values, name) {
+			return false
+		}
+	}
+	return true
+}
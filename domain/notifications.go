@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// @username, the username is whatever follows the @ until a space or punctuation
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@([\w.-]+)`)
+
+// Mentions returns the usernames mentioned in text, without duplicates
+func Mentions(text string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+
+	for _, m := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		username := strings.TrimRight(m[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
+
+// notifyMentions notifies the users mentioned in text, skipping the ones in previous (e.g the old title) and the author.
+// Only the creator and the assignee can see the todo, mentioning anyone else doesn't tell them about it
+func (d *Domain) notifyMentions(author *User, todo *Todo, text, previous string) {
+	already := make(map[string]bool)
+	for _, username := range Mentions(previous) {
+		already[username] = true
+	}
+
+	for _, username := range Mentions(text) {
+		if already[username] || username == author.Username {
+			continue
+		}
+
+		mentioned, err := d.DB.UserRepo.GetByUsername(username)
+		if err != nil || !todo.IsOwner(mentioned) {
+			continue
+		}
+
+		todoID := todo.ID
+		d.notify(mentioned.ID, Notification{
+			Kind:    NotificationMentioned,
+			Message: fmt.Sprintf("%s mentioned you in %q", author.Username, todo.Title),
+			TodoID:  &todoID,
+		})
+	}
+}
+
+// ListNotifications returns the inbox of the user, unread first
+func (d *Domain) ListNotifications(user *User) ([]*Notification, error) {
+	notifications, err := d.DB.NotificationRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (d *Domain) CountUnreadNotifications(user *User) (int, error) {
+	return d.DB.NotificationRepo.CountUnread(user.ID)
+}
+
+func (d *Domain) GetNotificationByID(id int64) (*Notification, error) {
+	notification, err := d.DB.NotificationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (d *Domain) MarkNotificationRead(notification *Notification) (*Notification, error) {
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	return d.DB.NotificationRepo.MarkRead(notification)
+}
+
+func (d *Domain) MarkAllNotificationsRead(user *User) error {
+	return d.DB.NotificationRepo.MarkAllRead(user.ID)
+}
+
+func (n *Notification) IsOwner(user *User) bool {
+	return n.UserID == user.ID
+}
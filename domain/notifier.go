@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"log"
+	"time"
+)
+
+// A Notification is something a user should know about (e.g a snoozed todo is back).
+// The InboxNotifier stores them, so users can read them later
+type Notification struct {
+	ID      int64  `json:"id"`
+	UserID  int64  `json:"-"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	TodoID  *int64 `json:"todoId"`
+
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+const (
+	NotificationTodoWokeUp   = "todo_woke_up"
+	NotificationTodoAssigned = "todo_assigned"
+	NotificationMentioned    = "mentioned"
+)
+
+// A Notifier delivers notifications to a user. The Domain works without one
+type Notifier interface {
+	Notify(userID int64, notification Notification) error
+}
+
+// LogNotifier only writes the notifications to the log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(userID int64, notification Notification) error {
+	log.Printf("notification for user %d: [%s] %s", userID, notification.Kind, notification.Message)
+	return nil
+}
+
+// InboxNotifier saves the notifications in the inbox of the user (see notifications.go)
+type InboxNotifier struct {
+	Repo NotificationRepo
+}
+
+func (i *InboxNotifier) Notify(userID int64, notification Notification) error {
+	notification.UserID = userID
+
+	_, err := i.Repo.Create(&notification)
+	return err
+}
+
+// notify sends the notification if we have a notifier. Every event (mentions, assignments, snoozes...) goes through it.
+// A failed notification never fails the action behind it
+func (d *Domain) notify(userID int64, notification Notification) {
+	if d.Notifier == nil {
+		return
+	}
+
+	if err := d.Notifier.Notify(userID, notification); err != nil {
+		log.Printf("cannot notify user %d: %v", userID, err)
+	}
+}
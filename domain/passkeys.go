@@ -0,0 +1,330 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"todo/webauthn"
+)
+
+const (
+	// ceremony tokens keep the challenge between begin and finish
+	TokenTypePasskeyRegistration = "passkey_registration"
+	TokenTypePasskeyLogin        = "passkey_login"
+
+	PasskeyCeremonyTTL = 5 * time.Minute
+)
+
+// A Passkey is a WebAuthn credential the user logs in with
+type Passkey struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"-"`
+	Name         string     `json:"name"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"` // COSE_Key
+	SignCount    int64      `json:"-" pg:",use_zero"`
+	Transports   []string   `json:"transports" pg:",array"`
+	AAGUID       []byte     `json:"-" pg:"aaguid"`
+	LastUsedAt   *time.Time `json:"lastUsedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func (p *Passkey) IsOwner(user *User) bool {
+	return p.UserID == user.ID
+}
+
+func (p *Passkey) descriptor() webauthn.CredentialDescriptor {
+	return webauthn.CredentialDescriptor{
+		Type:       "public-key",
+		ID:         p.CredentialID,
+		Transports: p.Transports,
+	}
+}
+
+// userHandle identifies the user to the authenticator, it must not be personal information
+func userHandle(user *User) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(user.ID))
+	return handle
+}
+
+// PasskeyCeremony is the answer of the begin endpoints. The client passes the options to the
+// browser and sends the session token back with the result
+type PasskeyCeremony struct {
+	SessionToken string      `json:"sessionToken"`
+	PublicKey    interface{} `json:"publicKey"`
+}
+
+func (d *Domain) BeginPasskeyRegistration(user *User) (*PasskeyCeremony, error) {
+	if d.WebAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	passkeys, err := d.DB.PasskeyRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the authenticators that already have a passkey of the user don't create another one
+	exclude := make([]webauthn.CredentialDescriptor, len(passkeys))
+	for i, passkey := range passkeys {
+		exclude[i] = passkey.descriptor()
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionToken, err := d.signCeremony(TokenTypePasskeyRegistration, user.ID, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	options := d.WebAuthn.CreationOptions(webauthn.UserEntity{
+		ID:          userHandle(user),
+		Name:        user.Email,
+		DisplayName: user.Username,
+	}, challenge, exclude)
+
+	return &PasskeyCeremony{SessionToken: sessionToken, PublicKey: options}, nil
+}
+
+type FinishPasskeyRegistrationPayload struct {
+	SessionToken string                       `json:"sessionToken"`
+	Name         string                       `json:"name"` // optional, to tell the passkeys apart
+	Credential   webauthn.AttestationResponse `json:"credential"`
+}
+
+func (f *FinishPasskeyRegistrationPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("sessionToken", f.SessionToken)
+	v.MustBeNotEmpty("credential", f.Credential.ID)
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) FinishPasskeyRegistration(user *User, payload FinishPasskeyRegistrationPayload) (*Passkey, error) {
+	if d.WebAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	userID, challenge, err := d.openCeremony(TokenTypePasskeyRegistration, payload.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != user.ID {
+		return nil, ErrInvalidToken
+	}
+
+	credential, err := d.WebAuthn.VerifyRegistration(payload.Credential, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.DB.PasskeyRepo.GetByCredentialID(credential.ID); err == nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	}
+
+	name := payload.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	return d.DB.PasskeyRepo.Create(&Passkey{
+		UserID:       user.ID,
+		Name:         name,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Transports:   credential.Transports,
+		AAGUID:       credential.AAGUID,
+	})
+}
+
+// BeginPasskeyLoginPayload can name the account, otherwise the browser offers the discoverable passkeys
+type BeginPasskeyLoginPayload struct {
+	Email string `json:"email"`
+}
+
+func (b *BeginPasskeyLoginPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	if b.Email != "" {
+		v.MustBeValidEmail("email", b.Email)
+	}
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) BeginPasskeyLogin(payload BeginPasskeyLoginPayload) (*PasskeyCeremony, error) {
+	if d.WebAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	// unknown emails get the same answer as discoverable logins, so accounts can't be enumerated
+	var allow []webauthn.CredentialDescriptor
+	if payload.Email != "" {
+		if user, err := d.DB.UserRepo.GetByEmail(d.normalizeEmail(payload.Email)); err == nil {
+			passkeys, err := d.DB.PasskeyRepo.ListByUser(user.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, passkey := range passkeys {
+				allow = append(allow, passkey.descriptor())
+			}
+		}
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionToken, err := d.signCeremony(TokenTypePasskeyLogin, 0, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCeremony{
+		SessionToken: sessionToken,
+		PublicKey:    d.WebAuthn.RequestOptions(challenge, allow),
+	}, nil
+}
+
+type FinishPasskeyLoginPayload struct {
+	SessionToken string                     `json:"sessionToken"`
+	Credential   webauthn.AssertionResponse `json:"credential"`
+}
+
+func (f *FinishPasskeyLoginPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("sessionToken", f.SessionToken)
+	v.MustBeNotEmpty("credential", f.Credential.ID)
+
+	return v.IsValid(), v.errors
+}
+
+// FinishPasskeyLogin returns the owner of the passkey that signed the challenge
+func (d *Domain) FinishPasskeyLogin(payload FinishPasskeyLoginPayload) (*User, error) {
+	if d.WebAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	_, challenge, err := d.openCeremony(TokenTypePasskeyLogin, payload.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey, err := d.DB.PasskeyRepo.GetByCredentialID(payload.Credential.RawID)
+	if err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	user, err := d.DB.UserRepo.GetByID(passkey.UserID)
+	if err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	if handle := payload.Credential.Response.UserHandle; len(handle) > 0 && string(handle) != string(userHandle(user)) {
+		return nil, ErrInvalidCredential
+	}
+
+	signCount, err := d.WebAuthn.VerifyAssertion(payload.Credential, challenge, &webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: uint32(passkey.SignCount),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	passkey.SignCount = int64(signCount)
+	passkey.LastUsedAt = &now
+
+	if err := d.DB.PasskeyRepo.UpdateSignCount(passkey); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (d *Domain) ListPasskeys(user *User) ([]*Passkey, error) {
+	return d.DB.PasskeyRepo.ListByUser(user.ID)
+}
+
+func (d *Domain) GetPasskeyByID(id int64) (*Passkey, error) {
+	return d.DB.PasskeyRepo.GetByID(id)
+}
+
+func (d *Domain) DeletePasskey(passkey *Passkey, user *User) error {
+	if err := mustOwn(passkey, user); err != nil {
+		return err
+	}
+
+	return d.DB.PasskeyRepo.Delete(passkey)
+}
+
+// signCeremony keeps the challenge in a signed token instead of a server side session
+func (d *Domain) signCeremony(typ string, userID int64, challenge []byte) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	return d.Keys.Sign(jwt.MapClaims{
+		"id":  userID,
+		"exp": time.Now().Add(PasskeyCeremonyTTL).Unix(),
+		"jti": jti,
+		"typ": typ,
+		"chl": base64.RawURLEncoding.EncodeToString(challenge),
+	})
+}
+
+// openCeremony returns the user and challenge of the ceremony token, which only works once
+func (d *Domain) openCeremony(typ, sessionToken string) (int64, []byte, error) {
+	token, err := d.Keys.Parse(sessionToken)
+	if err != nil {
+		return 0, nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != typ {
+		return 0, nil, ErrInvalidToken
+	}
+
+	id, _ := claims["id"].(float64)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	encoded, _ := claims["chl"].(string)
+
+	challenge, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || jti == "" || len(challenge) == 0 {
+		return 0, nil, ErrInvalidToken
+	}
+
+	if d.Revocations != nil {
+		revoked, err := d.Revocations.IsRevoked(jti)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if revoked {
+			return 0, nil, ErrInvalidToken
+		}
+
+		if err := d.Revocations.Revoke(jti, time.Unix(int64(exp), 0)); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return int64(id), challenge, nil
+}
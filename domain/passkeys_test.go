@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"todo/domain"
+	"todo/webauthn"
+	"todo/webauthn/webauthntest"
+)
+
+func TestPasskeyLogin(t *testing.T) {
+	d, _ := newTestDomain(t)
+	user := d.DB.UserRepo.(*memoryUserRepo).add(&domain.User{Username: "bob", Email: "bob@example.com"})
+	authenticator := webauthntest.New(testOrigin)
+
+	registration, err := d.BeginPasskeyRegistration(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attestation, err := authenticator.Create(registration.PublicKey.(*webauthn.CreationOptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.FinishPasskeyRegistration(user, domain.FinishPasskeyRegistrationPayload{
+		SessionToken: registration.SessionToken,
+		Credential:   *attestation,
+	}); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+
+	// login runs the ceremony with the authenticator, tamper changes the response before it's sent
+	login := func(a *webauthntest.Authenticator, tamper func(*domain.FinishPasskeyLoginPayload)) (*domain.User, error) {
+		ceremony, err := d.BeginPasskeyLogin(domain.BeginPasskeyLoginPayload{Email: "Bob@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertion, err := a.Get(ceremony.PublicKey.(*webauthn.RequestOptions))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := domain.FinishPasskeyLoginPayload{SessionToken: ceremony.SessionToken, Credential: *assertion}
+		if tamper != nil {
+			tamper(&payload)
+		}
+
+		return d.FinishPasskeyLogin(payload)
+	}
+
+	clone := authenticator.Clone()
+
+	loggedIn, err := login(authenticator, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if loggedIn.ID != user.ID {
+		t.Fatalf("logged in as %d, want %d", loggedIn.ID, user.ID)
+	}
+
+	t.Run("replayed ceremony", func(t *testing.T) {
+		var replayed domain.FinishPasskeyLoginPayload
+		if _, err := login(authenticator, func(p *domain.FinishPasskeyLoginPayload) { replayed = *p }); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := d.FinishPasskeyLogin(replayed); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("got %v, want %v", err, domain.ErrInvalidToken)
+		}
+	})
+
+	t.Run("bad challenge", func(t *testing.T) {
+		other, err := d.BeginPasskeyLogin(domain.BeginPasskeyLoginPayload{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = login(authenticator, func(p *domain.FinishPasskeyLoginPayload) { p.SessionToken = other.SessionToken })
+		if !errors.Is(err, webauthn.ErrChallengeMismatch) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrChallengeMismatch)
+		}
+	})
+
+	t.Run("bad origin", func(t *testing.T) {
+		phishing := authenticator.Clone()
+		phishing.Origin = "https://todo.example.com.evil.example"
+
+		if _, err := login(phishing, nil); !errors.Is(err, webauthn.ErrOriginNotAllowed) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrOriginNotAllowed)
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		_, err := login(authenticator, func(p *domain.FinishPasskeyLoginPayload) {
+			signature := p.Credential.Response.Signature
+			signature[len(signature)-1] ^= 0xff
+		})
+		if !errors.Is(err, webauthn.ErrInvalidSignature) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrInvalidSignature)
+		}
+	})
+
+	t.Run("sign count going backwards", func(t *testing.T) {
+		if _, err := login(clone, nil); !errors.Is(err, webauthn.ErrSignCountNotIncreased) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrSignCountNotIncreased)
+		}
+	})
+}
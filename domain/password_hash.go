@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A PasswordHasher hashes the passwords. The hashes carry the algorithm and its parameters
+// (PHC string format), so passwords hashed with older settings still verify and can be upgraded
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Recognizes tells whether the hash was made by this algorithm, whatever the parameters
+	Recognizes(hash string) bool
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash is true when the hash was made by another algorithm or with other parameters
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher was the only hasher before argon2id, its hashes keep the $2a$ format
+type BcryptHasher struct {
+	Cost int
+}
+
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b *BcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *BcryptHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (b *BcryptHasher) NeedsRehash(hash string) bool {
+	if !b.Recognizes(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// Argon2idHasher makes hashes like $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// NewArgon2idHasher has the parameters recommended by OWASP
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (a *Argon2idHasher) Verify(hash, password string) (bool, error) {
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (a *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != a.Memory || params.Iterations != a.Iterations || params.Parallelism != a.Parallelism ||
+		uint32(len(salt)) != a.SaltLength || uint32(len(key)) != a.KeyLength
+}
+
+func parseArgon2idHash(hash string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=19456,t=2,p=1", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	params := new(Argon2idHasher)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// PasswordHasherFromEnv reads PASSWORD_HASHER, argon2id (the default) or bcrypt
+func PasswordHasherFromEnv() (PasswordHasher, error) {
+	switch os.Getenv("PASSWORD_HASHER") {
+	case "", "argon2id":
+		return NewArgon2idHasher(), nil
+	case "bcrypt":
+		return &BcryptHasher{Cost: bcrypt.DefaultCost}, nil
+	default:
+		return nil, ErrUnknownPasswordHasher
+	}
+}
+
+func (d *Domain) passwordHasher() PasswordHasher {
+	if d.PasswordHasher == nil {
+		return NewArgon2idHasher()
+	}
+	return d.PasswordHasher
+}
+
+// verifyPassword checks the password with the algorithm of the hash, the hasher in use or an older one
+func (d *Domain) verifyPassword(hash, password string) (bool, error) {
+	for _, hasher := range []PasswordHasher{d.passwordHasher(), NewArgon2idHasher(), &BcryptHasher{}} {
+		if hasher.Recognizes(hash) {
+			return hasher.Verify(hash, password)
+		}
+	}
+
+	return false, ErrUnknownPasswordHash
+}
+
+// checkPassword returns ErrInvalidCredential when the password is not the one of the user
+func (d *Domain) checkPassword(user *User, password string) error {
+	ok, err := d.verifyPassword(user.Password, password)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidCredential
+	}
+
+	return nil
+}
+
+// rehashPassword upgrades the hash of the user to the current hasher, it needs the password so it
+// happens on login
+func (d *Domain) rehashPassword(user *User, password string) error {
+	hasher := d.passwordHasher()
+	if !hasher.NeedsRehash(user.Password) {
+		return nil
+	}
+
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hash
+	return d.DB.UserRepo.UpdatePassword(user)
+}
+
+// dummyPasswordHash is verified when the email is unknown, so it takes as long as a wrong password
+var dummyPasswordHash, _ = NewArgon2idHasher().Hash("dummy password")
@@ -0,0 +1,120 @@
+package domain_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"todo/domain"
+)
+
+func TestArgon2idHash(t *testing.T) {
+	hasher := testHasher()
+
+	hash, err := hasher.Hash("tangerine-orbit-57")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("hash %q is not in the PHC format", hash)
+	}
+
+	parts := strings.Split(hash, "$")
+
+	tests := []struct {
+		name        string
+		hash        string
+		password    string
+		want        bool
+		wantErr     error
+		needsRehash bool
+	}{
+		{name: "right password", hash: hash, password: "tangerine-orbit-57", want: true},
+		{name: "wrong password", hash: hash, password: "tangerine-orbit-58"},
+		// the key was made with other parameters, it can't match
+		{name: "other parameters", hash: strings.Replace(hash, "m=64,t=1", "m=128,t=1", 1),
+			password: "tangerine-orbit-57", needsRehash: true},
+		{name: "other version", hash: strings.Replace(hash, "v=19", "v=16", 1), password: "tangerine-orbit-57",
+			wantErr: domain.ErrUnknownPasswordHash, needsRehash: true},
+		{name: "missing part", hash: strings.Join(parts[:5], "$"), password: "tangerine-orbit-57",
+			wantErr: domain.ErrUnknownPasswordHash, needsRehash: true},
+		{name: "bad salt", hash: strings.Join([]string{"", parts[1], parts[2], parts[3], "!!", parts[5]}, "$"),
+			password: "tangerine-orbit-57", wantErr: domain.ErrUnknownPasswordHash, needsRehash: true},
+		{name: "empty key", hash: strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], ""}, "$"),
+			password: "tangerine-orbit-57", wantErr: domain.ErrUnknownPasswordHash, needsRehash: true},
+		{name: "bad parameters", hash: strings.Replace(hash, "m=64,t=1,p=1", "m=64;t=1", 1), password: "tangerine-orbit-57",
+			wantErr: domain.ErrUnknownPasswordHash, needsRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := hasher.Verify(tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			if ok != tt.want {
+				t.Errorf("verified %v, want %v", ok, tt.want)
+			}
+
+			if got := hasher.NeedsRehash(tt.hash); got != tt.needsRehash {
+				t.Errorf("needs rehash %v, want %v", got, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestRehashOnLogin(t *testing.T) {
+	const password = "tangerine-orbit-57"
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	older := testHasher()
+	older.Iterations = 2
+	olderHash, err := older.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+	}{
+		{name: "bcrypt", hash: string(bcryptHash), rehash: true},
+		{name: "argon2id with older parameters", hash: olderHash, rehash: true},
+		{name: "current argon2id", hash: hashPassword(t, password)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDomain(t)
+			users := d.DB.UserRepo.(*memoryUserRepo)
+			users.add(&domain.User{Username: "bob", Email: "bob@example.com", Password: tt.hash})
+
+			user, err := d.Login(domain.LoginPayload{Email: "bob@example.com", Password: password}, "192.0.2.1")
+			if err != nil {
+				t.Fatalf("login failed: %v", err)
+			}
+
+			if rehashed := users.passwordUpdates == 1; rehashed != tt.rehash {
+				t.Fatalf("rehashed %v, want %v", rehashed, tt.rehash)
+			}
+
+			if d.PasswordHasher.NeedsRehash(user.Password) {
+				t.Errorf("the hash %q wasn't upgraded", user.Password)
+			}
+
+			// the new hash still verifies, so the next login works
+			if _, err := d.Login(domain.LoginPayload{Email: "bob@example.com", Password: password}, "192.0.2.1"); err != nil {
+				t.Errorf("second login failed: %v", err)
+			}
+		})
+	}
+}
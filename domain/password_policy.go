@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A PasswordPolicy has the rules of the passwords the users choose (register, change, reset)
+type PasswordPolicy struct {
+	MinLength int               // in characters
+	MaxLength int               // in characters, hashing very long passwords is slow
+	MinScore  int               // 0 to 4, see PasswordStrength
+	History   int               // how many of the last passwords can't be reused, 0 allows them
+	Breached  BreachedPasswords // optional, see the breached package
+}
+
+// BreachedPasswords tells whether a password appeared in a data breach
+type BreachedPasswords interface {
+	IsBreached(password string) (bool, error)
+}
+
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength: 8,
+		MaxLength: 128,
+		MinScore:  2,
+		History:   5,
+	}
+}
+
+// PasswordPolicyFromEnv reads PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH, PASSWORD_MIN_SCORE and
+// PASSWORD_HISTORY, the defaults apply to the ones that are not set
+func PasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+
+	for env, value := range map[string]*int{
+		"PASSWORD_MIN_LENGTH": &policy.MinLength,
+		"PASSWORD_MAX_LENGTH": &policy.MaxLength,
+		"PASSWORD_MIN_SCORE":  &policy.MinScore,
+		"PASSWORD_HISTORY":    &policy.History,
+	} {
+		raw := os.Getenv(env)
+		if raw == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, ErrInvalidPasswordPolicy{env}
+		}
+		*value = n
+	}
+
+	if policy.MaxLength == 0 || policy.MaxLength < policy.MinLength {
+		return nil, ErrInvalidPasswordPolicy{"PASSWORD_MAX_LENGTH"}
+	}
+
+	if policy.MinScore > len(passwordScoreGuesses) {
+		return nil, ErrInvalidPasswordPolicy{"PASSWORD_MIN_SCORE"}
+	}
+
+	return policy, nil
+}
+
+func (d *Domain) passwordPolicy() *PasswordPolicy {
+	if d.PasswordPolicy == nil {
+		return DefaultPasswordPolicy()
+	}
+	return d.PasswordPolicy
+}
+
+// validateNewPassword applies the password policy. The user isn't saved yet on registration,
+// it only has the username and the email
+func (d *Domain) validateNewPassword(user *User, password string) error {
+	policy := d.passwordPolicy()
+	v := NewValidator()
+
+	if !v.mustBeLongEnough("password", password, policy.MinLength) ||
+		!v.mustBeShortEnough("password", password, policy.MaxLength) ||
+		!v.mustNotLookLike("password", password, "username", user.Username) ||
+		!v.mustNotLookLike("password", password, "email", user.Email) ||
+		!v.mustNotLookLike("password", password, "email", emailLocalPart(user.Email)) {
+		return ErrValidation{Errors: v.errors}
+	}
+
+	if score := PasswordStrength(password, user.Username, user.Email, emailLocalPart(user.Email)); score < policy.MinScore {
+		v.errors["password"] = ErrPasswordTooWeak{score: score, min: policy.MinScore}.Error()
+		return ErrValidation{Errors: v.errors}
+	}
+
+	if policy.Breached != nil {
+		breached, err := policy.Breached.IsBreached(password)
+		if err != nil {
+			return err
+		}
+
+		if breached {
+			v.errors["password"] = ErrPasswordBreached.Error()
+			return ErrValidation{Errors: v.errors}
+		}
+	}
+
+	if user.ID != 0 && policy.History > 0 {
+		reused, err := d.isRecentPassword(user, password, policy.History)
+		if err != nil {
+			return err
+		}
+
+		if reused {
+			v.errors["password"] = ErrPasswordReused{amount: policy.History}.Error()
+			return ErrValidation{Errors: v.errors}
+		}
+	}
+
+	return nil
+}
+
+// isRecentPassword compares the password with the current one and the previous ones
+func (d *Domain) isRecentPassword(user *User, password string, history int) (bool, error) {
+	hashes := []string{user.Password}
+
+	previous, err := d.DB.PreviousPasswordRepo.ListRecent(user.ID, history)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range previous {
+		hashes = append(hashes, p.PasswordHash)
+	}
+
+	for _, hash := range hashes {
+		if ok, _ := d.verifyPassword(hash, password); ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// A PreviousPassword is the hash of a password the user had, to prevent its reuse
+type PreviousPassword struct {
+	ID           int64
+	UserID       int64
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+// rememberPassword keeps the current password of the user for the history of the policy.
+// It's called every time the password is set
+func (d *Domain) rememberPassword(user *User) error {
+	history := d.passwordPolicy().History
+	if history == 0 {
+		return nil
+	}
+
+	_, err := d.DB.PreviousPasswordRepo.Create(&PreviousPassword{
+		UserID:       user.ID,
+		PasswordHash: user.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.DB.PreviousPasswordRepo.DeleteOlder(user.ID, history)
+}
+
+// mustBeLongEnough counts characters, not bytes like MustBeLongerThan
+func (v *Validator) mustBeLongEnough(field, value string, min int) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if utf8.RuneCountInString(value) < min {
+		v.errors[field] = ErrNotLongEnough{field: field, amount: min}.Error()
+		return false
+	}
+
+	return true
+}
+
+func (v *Validator) mustBeShortEnough(field, value string, max int) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if utf8.RuneCountInString(value) > max {
+		v.errors[field] = ErrTooLong{field: field, amount: max}.Error()
+		return false
+	}
+
+	return true
+}
+
+// mustNotLookLike rejects a password that contains the data of the user or is part of it,
+// the common substitutions (p4ssw0rd) included
+func (v *Validator) mustNotLookLike(field, password, otherField, other string) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	other = strings.ToLower(other)
+	if utf8.RuneCountInString(other) < 3 {
+		return true
+	}
+
+	for _, candidate := range unleet([]rune(strings.ToLower(password))) {
+		if strings.Contains(candidate.word, other) || strings.Contains(other, candidate.word) {
+			v.errors[field] = ErrPasswordTooSimilar{field: otherField}.Error()
+			return false
+		}
+	}
+
+	return true
+}
+
+func emailLocalPart(email string) string {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return email[:at]
+	}
+	return email
+}
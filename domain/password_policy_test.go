@@ -0,0 +1,97 @@
+package domain_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"todo/domain"
+)
+
+// breachedList is the breached passwords of the tests
+type breachedList []string
+
+func (b breachedList) IsBreached(password string) (bool, error) {
+	for _, breached := range b {
+		if password == breached {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string // in the error of the password field, empty when it's accepted
+	}{
+		{name: "strong", password: "tangerine-orbit-57-quietly"},
+		{name: "too short", password: "x7#kQ", want: "not long enough"},
+		{name: "too long", password: strings.Repeat("tangerine-orbit-57-", 7), want: "too long"},
+		{name: "128 characters", password: strings.Repeat("tangerine-orbit-57-", 6) + "quietly-blue-7"},
+		{name: "contains the username", password: "bobbington-2024!", want: "similar to your username"},
+		{name: "leet username", password: "b0bb1ngt0n-rocks", want: "similar to your username"},
+		{name: "contains the email", password: "builder-Zq9-wharf", want: "similar to your email"},
+		{name: "easy to guess", password: "password123", want: "too easy to guess"},
+		{name: "breached", password: "correct-horse-battery-staple", want: domain.ErrPasswordBreached.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestDomain(t)
+			policy := domain.DefaultPasswordPolicy()
+			policy.Breached = breachedList{"correct-horse-battery-staple"}
+			d.PasswordPolicy = policy
+
+			_, err := d.Register(domain.RegisterPayload{
+				Username: "bobbington",
+				Email:    "builder@example.com",
+				Password: tt.password,
+			})
+
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+
+			var invalid domain.ErrValidation
+			if !errors.As(err, &invalid) {
+				t.Fatalf("got %v, want a validation error", err)
+			}
+			if !strings.Contains(invalid.Errors["password"], tt.want) {
+				t.Errorf("got %q, want %q", invalid.Errors["password"], tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "longer passwords", env: map[string]string{"PASSWORD_MIN_LENGTH": "12", "PASSWORD_MAX_LENGTH": "256"}},
+		{name: "max below min", env: map[string]string{"PASSWORD_MIN_LENGTH": "12", "PASSWORD_MAX_LENGTH": "10"}, wantErr: true},
+		{name: "no max", env: map[string]string{"PASSWORD_MAX_LENGTH": "0"}, wantErr: true},
+		{name: "not a number", env: map[string]string{"PASSWORD_HISTORY": "five"}, wantErr: true},
+		{name: "score above 4", env: map[string]string{"PASSWORD_MIN_SCORE": "5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_MAX_LENGTH", "PASSWORD_MIN_SCORE", "PASSWORD_HISTORY"} {
+				t.Setenv(env, tt.env[env])
+			}
+
+			_, err := domain.PasswordPolicyFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
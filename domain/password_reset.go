@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const PasswordResetTTL = time.Hour
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+func (f *ForgotPasswordPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("email", f.Email)
+	v.MustBeValidEmail("email", f.Email)
+
+	return v.IsValid(), v.errors
+}
+
+// RequestPasswordReset emails a reset link to the user. It returns right away and does the work in
+// the background: only the existing accounts get a token and an email, the response time mustn't tell
+// them apart. Unknown emails are not an error, the caller answers the same either way
+func (d *Domain) RequestPasswordReset(payload ForgotPasswordPayload) {
+	email := d.normalizeEmail(payload.Email)
+
+	go func() {
+		user, err := d.DB.UserRepo.GetByEmail(email)
+		if err != nil {
+			if !errors.Is(err, ErrNoResult) {
+				log.Printf("cannot start the password reset: %v", err)
+			}
+			return
+		}
+
+		if err := d.sendPasswordReset(user); err != nil {
+			log.Printf("cannot start the password reset: %v", err)
+		}
+	}()
+}
+
+func (d *Domain) sendPasswordReset(user *User) error {
+	token, err := d.newUserToken(user, TokenPurposePasswordReset, user.Email, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nchoose a new password by opening %s\n\nThe link is valid for %v. If you didn't ask for it, ignore this email.\n",
+			user.Username, d.appLink("/reset-password", token), PasswordResetTTL),
+	})
+
+	return nil
+}
+
+type ResetPasswordPayload struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+func (r *ResetPasswordPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("token", r.Token)
+	v.mustBeValidNewPassword(r.Password, r.ConfirmPassword)
+
+	return v.IsValid(), v.errors
+}
+
+// ResetPassword sets the new password and logs the user out of every session
+func (d *Domain) ResetPassword(payload ResetPasswordPayload) error {
+	// the token is only used once the new password passed the policy, so the user can try another one
+	token, err := d.findUserToken(TokenPurposePasswordReset, payload.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := d.DB.UserRepo.GetByID(token.UserID)
+	if err != nil {
+		return err
+	}
+
+	// the email changed after the link was sent
+	if token.Data != user.Email {
+		return ErrInvalidToken
+	}
+
+	if err := d.validateNewPassword(user, payload.Password); err != nil {
+		return err
+	}
+
+	if _, err := d.consumeUserToken(TokenPurposePasswordReset, payload.Token); err != nil {
+		return err
+	}
+
+	password, err := d.setPassword(payload.Password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = *password
+	user.UpdatedAt = time.Now()
+
+	if err := d.DB.UserRepo.UpdatePassword(user); err != nil {
+		return err
+	}
+
+	if err := d.rememberPassword(user); err != nil {
+		return err
+	}
+
+	return d.LogoutEverywhere(user)
+}
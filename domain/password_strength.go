@@ -0,0 +1,349 @@
+package domain
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// A zxcvbn style estimation of the guesses needed to find a password. The password is split in the
+// patterns an attacker tries first (common passwords, the user's own data, sequences, repeats,
+// keyboard rows, years) and in bruteforced characters; the cheapest split gives the guesses
+
+// score thresholds of zxcvbn, in guesses
+var passwordScoreGuesses = []float64{1e3, 1e6, 1e8, 1e10}
+
+const (
+	// the longest dictionary word we look for, it bounds the substrings we check
+	maxPasswordWordLength = 20
+	// guesses of each character outside of a pattern, as zxcvbn does
+	bruteforceGuessesPerChar = 10
+)
+
+// most common passwords and words in them, by rank
+var commonPasswords = rankedWords(`password 123456 123456789 qwerty 12345678 111111 1234567890 1234567
+abc123 password1 iloveyou 000000 123123 admin letmein welcome monkey dragon football baseball
+princess sunshine master shadow superman batman trustno1 starwars pokemon michael jordan
+hello freedom whatever qazwsx ninja mustang access flower lovely charlie donald login secret
+summer winter spring autumn computer internet soccer hockey killer hunter ranger buster thomas
+tigger robert george harley pepper daniel andrew joshua maggie cheese chelsea yankees
+purple orange banana apple diamond ginger silver golden matrix merlin cookie coffee jessica
+ashley nicole hannah amanda jennifer michelle love angel baby family friends forever heaven
+google facebook twitter linkedin microsoft changeme default guest root test testing todo
+passw0rd p@ssword pass asdf zxcvbn qwertyuiop asdfgh football1 monkey1 dragon1 liverpool
+arsenal barcelona madrid london paris berlin america canada mexico brazil money power
+blessed jesus god lucky happy smile magic music guitar rock star sweet cherry peanut
+butterfly tiger lion eagle wolf bear shark falcon phoenix hello123 welcome1 admin123 qwerty123`)
+
+func rankedWords(words string) map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(words) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}
+
+// characters attackers commonly substitute for letters
+var leetSubstitutions = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "!@#$%^&*()"}
+
+type passwordMatch struct {
+	i, j    int // runes [i, j) of the password
+	guesses float64
+}
+
+// PasswordStrength scores the password from 0 (guessed right away) to 4 (very hard to guess).
+// userInputs are the data of the user an attacker would try first (username, email...)
+func PasswordStrength(password string, userInputs ...string) int {
+	guesses := estimatePasswordGuesses(password, userInputs)
+
+	for score, threshold := range passwordScoreGuesses {
+		if guesses < threshold {
+			return score
+		}
+	}
+	return len(passwordScoreGuesses)
+}
+
+func estimatePasswordGuesses(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 1
+	}
+
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	dictionary := map[string]int{}
+	for word, rank := range commonPasswords {
+		dictionary[word] = rank
+	}
+	for _, input := range userInputs {
+		if input = strings.ToLower(input); len(input) >= 3 {
+			dictionary[input] = 1
+		}
+	}
+
+	var matches []passwordMatch
+	matches = append(matches, dictionaryMatches(runes, lower, dictionary)...)
+	matches = append(matches, sequenceMatches(lower)...)
+	matches = append(matches, repeatMatches(lower)...)
+	matches = append(matches, keyboardMatches(lower)...)
+	matches = append(matches, yearMatches(lower)...)
+
+	// best[k] is the fewest guesses of the first k runes
+	best := make([]float64, len(runes)+1)
+	best[0] = 1
+	for k := 1; k <= len(runes); k++ {
+		best[k] = best[k-1] * bruteforceGuessesPerChar
+		for _, m := range matches {
+			if m.j == k && best[m.i]*m.guesses < best[k] {
+				best[k] = best[m.i] * m.guesses
+			}
+		}
+	}
+
+	return best[len(runes)]
+}
+
+func dictionaryMatches(runes, lower []rune, dictionary map[string]int) []passwordMatch {
+	var matches []passwordMatch
+
+	for i := range lower {
+		for j := i + 3; j <= len(lower) && j-i <= maxPasswordWordLength; j++ {
+			for _, candidate := range unleet(lower[i:j]) {
+				rank, ok := dictionary[candidate.word]
+				if !ok {
+					continue
+				}
+
+				guesses := float64(rank) * uppercaseVariations(runes[i:j]) * math.Pow(2, float64(candidate.substitutions))
+				matches = append(matches, passwordMatch{i, j, guesses})
+			}
+		}
+	}
+
+	return matches
+}
+
+type unleeted struct {
+	word          string
+	substitutions int
+}
+
+// unleet returns the word with the substitutions undone, every way they can be read
+func unleet(word []rune) []unleeted {
+	variants := []unleeted{{}}
+
+	for _, r := range word {
+		letters, ok := leetSubstitutions[r]
+		if !ok {
+			for i := range variants {
+				variants[i].word += string(r)
+			}
+			continue
+		}
+
+		var next []unleeted
+		for _, v := range variants {
+			next = append(next, unleeted{v.word + string(r), v.substitutions})
+			for _, letter := range letters {
+				next = append(next, unleeted{v.word + string(letter), v.substitutions + 1})
+			}
+		}
+
+		// a word full of ambiguous characters isn't worth all the combinations
+		if len(next) > 64 {
+			next = next[:64]
+		}
+		variants = next
+	}
+
+	return variants
+}
+
+// uppercaseVariations is 1 for lowercase words, 2 for the usual capitalizations and more otherwise
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && unicode.IsUpper(word[0])) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// sequenceMatches finds runs like abc, 9876 or xyz
+func sequenceMatches(lower []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for i := 0; i+2 < len(lower); {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		for j < len(lower) && lower[j]-lower[j-1] == delta && (delta == 1 || delta == -1) && sameClass(lower[i], lower[j]) {
+			j++
+		}
+
+		if j-i >= 3 {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("az019", lower[i]):
+				base = 4
+			case unicode.IsDigit(lower[i]):
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+
+			matches = append(matches, passwordMatch{i, j, base * float64(j-i)})
+			i = j
+			continue
+		}
+		i++
+	}
+
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	return (unicode.IsDigit(a) && unicode.IsDigit(b)) || (unicode.IsLetter(a) && unicode.IsLetter(b))
+}
+
+// repeatMatches finds the same character repeated, like aaaa
+func repeatMatches(lower []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for i := 0; i < len(lower); {
+		j := i + 1
+		for j < len(lower) && lower[j] == lower[i] {
+			j++
+		}
+
+		if j-i >= 3 {
+			matches = append(matches, passwordMatch{i, j, bruteforceCardinality(lower[i:i+1]) * float64(j-i)})
+		}
+		i = j
+	}
+
+	return matches
+}
+
+// keyboardMatches finds runs of adjacent keys of a row, like qwer or lkjh
+func keyboardMatches(lower []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for _, row := range keyboardRows {
+		reversed := reverseString(row)
+
+		for i := range lower {
+			for j := i + 3; j <= len(lower) && j-i <= len(row); j++ {
+				part := string(lower[i:j])
+				if strings.Contains(row, part) || strings.Contains(reversed, part) {
+					matches = append(matches, passwordMatch{i, j, float64(len(keyboardRows)*len(row)) * float64(j-i)})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// yearMatches finds recent years, 1900 to 2039
+func yearMatches(lower []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for i := 0; i+4 <= len(lower); i++ {
+		year := string(lower[i : i+4])
+		if year >= "1900" && year <= "2039" && isDigits(year) {
+			matches = append(matches, passwordMatch{i, i + 4, 140})
+		}
+	}
+
+	return matches
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// bruteforceCardinality is the size of the alphabet of these characters
+func bruteforceCardinality(runes []rune) float64 {
+	var lower, upper, digits, symbols, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case r < 128:
+			symbols = true
+		default:
+			other = true
+		}
+	}
+
+	cardinality := 0.0
+	if lower {
+		cardinality += 26
+	}
+	if upper {
+		cardinality += 26
+	}
+	if digits {
+		cardinality += 10
+	}
+	if symbols {
+		cardinality += 33
+	}
+	if other {
+		cardinality += 100
+	}
+	return cardinality
+}
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// PersonalAccessTokenPrefix tells the personal access tokens apart from the JWTs, and makes them easy to spot in leaks
+const PersonalAccessTokenPrefix = "todo_pat_"
+
+// Scopes a personal access token can have. Write scopes also allow reading
+const (
+	ScopeTodosRead          = "todos:read"
+	ScopeTodosWrite         = "todos:write"
+	ScopeProjectsRead       = "projects:read"
+	ScopeProjectsWrite      = "projects:write"
+	ScopeTimeRead           = "time:read"
+	ScopeTimeWrite          = "time:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+var Scopes = []string{
+	ScopeTodosRead, ScopeTodosWrite,
+	ScopeProjectsRead, ScopeProjectsWrite,
+	ScopeTimeRead, ScopeTimeWrite,
+	ScopeNotificationsRead, ScopeNotificationsWrite,
+}
+
+// A PersonalAccessToken lets scripts use the api as the user, limited to its scopes
+type PersonalAccessToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes" pg:",array"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+func (p *PersonalAccessToken) IsOwner(user *User) bool {
+	return p.UserID == user.ID
+}
+
+func (p *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// HasScope tells if the token can access the resource, e.g HasScope("todos", false) to read todos
+func (p *PersonalAccessToken) HasScope(resource string, write bool) bool {
+	for _, scope := range p.Scopes {
+		if scope == resource+":write" || (!write && scope == resource+":read") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+type CreatePersonalAccessTokenPayload struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"` // optional, never expires without it
+}
+
+func (c *CreatePersonalAccessTokenPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("name", c.Name)
+	v.MustBeAtLeast("scopes", len(c.Scopes), 1)
+
+	for _, scope := range c.Scopes {
+		v.MustBeOneOf("scopes", scope, Scopes)
+	}
+
+	if c.ExpiresAt != nil {
+		v.MustBeAfter("expiresAt", *c.ExpiresAt, "now", time.Now())
+	}
+
+	return v.IsValid(), v.errors
+}
+
+// NewPersonalAccessToken carries the token itself, which is only shown on creation
+type NewPersonalAccessToken struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
+
+func (d *Domain) CreatePersonalAccessToken(user *User, payload CreatePersonalAccessTokenPayload) (*NewPersonalAccessToken, error) {
+	random, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	token := PersonalAccessTokenPrefix + random
+
+	pat, err := d.DB.PersonalAccessTokenRepo.Create(&PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      payload.Name,
+		Prefix:    token[:len(PersonalAccessTokenPrefix)+4],
+		TokenHash: hashToken(token),
+		Scopes:    payload.Scopes,
+		ExpiresAt: payload.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &NewPersonalAccessToken{PersonalAccessToken: pat, Token: token}, nil
+}
+
+func (d *Domain) ListPersonalAccessTokens(user *User) ([]*PersonalAccessToken, error) {
+	return d.DB.PersonalAccessTokenRepo.ListByUser(user.ID)
+}
+
+func (d *Domain) GetPersonalAccessTokenByID(id int64) (*PersonalAccessToken, error) {
+	return d.DB.PersonalAccessTokenRepo.GetByID(id)
+}
+
+type UpdatePersonalAccessTokenPayload struct {
+	Name string `json:"name"`
+}
+
+func (u *UpdatePersonalAccessTokenPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("name", u.Name)
+
+	return v.IsValid(), v.errors
+}
+
+// UpdatePersonalAccessToken renames the token. The scopes can't change, create another token instead
+func (d *Domain) UpdatePersonalAccessToken(pat *PersonalAccessToken, payload UpdatePersonalAccessTokenPayload, user *User) (*PersonalAccessToken, error) {
+	if err := mustOwn(pat, user); err != nil {
+		return nil, err
+	}
+
+	pat.Name = payload.Name
+	pat.UpdatedAt = time.Now()
+
+	return d.DB.PersonalAccessTokenRepo.Update(pat)
+}
+
+func (d *Domain) RevokePersonalAccessToken(pat *PersonalAccessToken, user *User) error {
+	if err := mustOwn(pat, user); err != nil {
+		return err
+	}
+
+	return d.DB.PersonalAccessTokenRepo.Delete(pat)
+}
+
+// AuthenticatePersonalAccessToken returns the user of the token and records its use
+func (d *Domain) AuthenticatePersonalAccessToken(token string) (*User, *PersonalAccessToken, error) {
+	pat, err := d.DB.PersonalAccessTokenRepo.GetByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	if pat.IsExpired(time.Now()) {
+		return nil, nil, ErrTokenExpired
+	}
+
+	user, err := d.DB.UserRepo.GetByID(pat.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if user.IsDisabled() {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if err := d.DB.PersonalAccessTokenRepo.TouchLastUsed(pat); err != nil {
+		return nil, nil, err
+	}
+
+	return user, pat, nil
+}
@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const EmailChangeTTL = 24 * time.Hour
+
+type UpdateProfilePayload struct {
+	Username    *string `json:"username"`
+	DisplayName *string `json:"displayName"`
+}
+
+func (u *UpdateProfilePayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	if u.Username != nil {
+		v.MustBeLongerThan("username", *u.Username, 3)
+		v.MustBeNotEmpty("username", *u.Username)
+		v.mustBeSafeUsername("username", *u.Username)
+	}
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) UpdateProfile(user *User, payload UpdateProfilePayload) (*User, error) {
+	if payload.Username != nil && NormalizeUsername(*payload.Username) != user.Username {
+		username := NormalizeUsername(*payload.Username)
+
+		// the user can change the case of their own username, or the other characters of its key
+		userExist, _ := d.DB.UserRepo.GetByUsernameKey(UsernameKey(username))
+		if userExist != nil && userExist.ID != user.ID {
+			return nil, ErrUserWithUsernameAlreadyExist
+		}
+
+		user.Username = username
+	}
+
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
+	}
+
+	user.UpdatedAt = time.Now()
+
+	return d.DB.UserRepo.Update(user)
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+func (c *ChangePasswordPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("currentPassword", c.CurrentPassword)
+	v.mustBeValidNewPassword(c.Password, c.ConfirmPassword)
+
+	return v.IsValid(), v.errors
+}
+
+// ChangePassword logs the user out everywhere, the caller gets new tokens for the current client
+func (d *Domain) ChangePassword(user *User, payload ChangePasswordPayload) error {
+	if err := d.checkPassword(user, payload.CurrentPassword); err != nil {
+		return ErrInvalidCredential
+	}
+
+	if err := d.validateNewPassword(user, payload.Password); err != nil {
+		return err
+	}
+
+	password, err := d.setPassword(payload.Password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = *password
+	user.UpdatedAt = time.Now()
+
+	if err := d.DB.UserRepo.UpdatePassword(user); err != nil {
+		return err
+	}
+
+	if err := d.rememberPassword(user); err != nil {
+		return err
+	}
+
+	// the user comes back with the new token generation
+	return d.LogoutEverywhere(user)
+}
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (c *ChangeEmailPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("email", c.Email)
+	v.MustBeValidEmail("email", c.Email)
+	v.MustBeNotEmpty("password", c.Password)
+
+	return v.IsValid(), v.errors
+}
+
+// RequestEmailChange sends a confirmation link to the new address, the email changes once it's opened
+func (d *Domain) RequestEmailChange(user *User, payload ChangeEmailPayload) error {
+	if err := d.checkPassword(user, payload.Password); err != nil {
+		return ErrInvalidCredential
+	}
+
+	email := d.normalizeEmail(payload.Email)
+
+	userExist, _ := d.DB.UserRepo.GetByEmail(email)
+	if userExist != nil && userExist.ID != user.ID {
+		return ErrUserWithEmailAlreadyExist
+	}
+
+	token, err := d.newUserToken(user, TokenPurposeEmailChange, email, EmailChangeTTL)
+	if err != nil {
+		return err
+	}
+
+	d.sendMail(Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hi %s,\n\nconfirm this is your new email address by opening %s\n\nThe link is valid for %v.\n",
+			user.Username, d.appLink("/confirm-email", token), EmailChangeTTL),
+	})
+
+	return nil
+}
+
+type ConfirmEmailChangePayload struct {
+	Token string `json:"token"`
+}
+
+func (c *ConfirmEmailChangePayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("token", c.Token)
+
+	return v.IsValid(), v.errors
+}
+
+// ConfirmEmailChange switches to the new email, which is verified by the link itself
+func (d *Domain) ConfirmEmailChange(payload ConfirmEmailChangePayload) (*User, error) {
+	token, err := d.consumeUserToken(TokenPurposeEmailChange, payload.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := d.DB.UserRepo.GetByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// someone took the address in the meantime
+	userExist, err := d.DB.UserRepo.GetByEmail(token.Data)
+	if err != nil && !errors.Is(err, ErrNoResult) {
+		return nil, err
+	}
+	if userExist != nil && userExist.ID != user.ID {
+		return nil, ErrUserWithEmailAlreadyExist
+	}
+
+	oldEmail := user.Email
+	now := time.Now()
+
+	user.Email = token.Data
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+
+	user, err = d.DB.UserRepo.UpdateEmail(user)
+	if err != nil {
+		return nil, err
+	}
+
+	d.sendMail(Message{
+		To:      oldEmail,
+		Subject: "Your email was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nthe email of your account is now %s. If you didn't change it, reset your password at %s\n",
+			user.Username, user.Email, d.AppURL+"/forgot-password"),
+	})
+
+	return user, nil
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// Projects group todos together, so tracked time can be reported per project
+type Project struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	UserID int64  `json:"userId"`
+
+	// nil means the project uses the DefaultWorkflow
+	Workflow *Workflow `json:"workflow"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CreateProjectPayload struct {
+	Name string `json:"name"`
+}
+
+func (c *CreateProjectPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("name", c.Name)
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) CreateProject(payload CreateProjectPayload, user *User) (*Project, error) {
+	data := &Project{
+		Name:   payload.Name,
+		UserID: user.ID,
+	}
+
+	project, err := d.DB.ProjectRepo.Create(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (d *Domain) GetProjectByID(id int64) (*Project, error) {
+	project, err := d.DB.ProjectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (d *Domain) ListProjects(user *User) ([]*Project, error) {
+	projects, err := d.DB.ProjectRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (p *Project) GetWorkflow() *Workflow {
+	if p.Workflow == nil {
+		return DefaultWorkflow
+	}
+
+	return p.Workflow
+}
+
+func (p *Project) IsOwner(user *User) bool {
+	return p.UserID == user.ID
+}
@@ -0,0 +1,194 @@
+package domain_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo/domain"
+	"todo/mailer"
+	"todo/webauthn"
+)
+
+// In memory repos for the tests of the domain. The embedded interface panics on the methods
+// a test doesn't need
+
+type memoryUserRepo struct {
+	domain.UserRepo
+
+	mu              sync.Mutex
+	users           []*domain.User
+	passwordUpdates int
+}
+
+func (m *memoryUserRepo) add(user *domain.User) *domain.User {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user.ID = int64(len(m.users) + 1)
+	m.users = append(m.users, user)
+	return user
+}
+
+func (m *memoryUserRepo) find(match func(*domain.User) bool) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if match(user) {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrNoResult
+}
+
+func (m *memoryUserRepo) GetByID(id int64) (*domain.User, error) {
+	return m.find(func(u *domain.User) bool { return u.ID == id })
+}
+
+func (m *memoryUserRepo) GetByEmail(email string) (*domain.User, error) {
+	return m.find(func(u *domain.User) bool { return strings.EqualFold(u.Email, email) })
+}
+
+func (m *memoryUserRepo) GetByUsername(username string) (*domain.User, error) {
+	return m.find(func(u *domain.User) bool { return strings.EqualFold(u.Username, username) })
+}
+
+func (m *memoryUserRepo) GetByUsernameKey(key string) (*domain.User, error) {
+	return m.find(func(u *domain.User) bool { return domain.UsernameKey(u.Username) == key })
+}
+
+func (m *memoryUserRepo) Create(user *domain.User) (*domain.User, error) {
+	return m.add(user), nil
+}
+
+func (m *memoryUserRepo) UpdatePassword(user *domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.passwordUpdates++
+	return nil
+}
+
+type memoryPasskeyRepo struct {
+	domain.PasskeyRepo
+
+	passkeys []*domain.Passkey
+}
+
+func (m *memoryPasskeyRepo) Create(passkey *domain.Passkey) (*domain.Passkey, error) {
+	passkey.ID = int64(len(m.passkeys) + 1)
+	m.passkeys = append(m.passkeys, passkey)
+	return passkey, nil
+}
+
+func (m *memoryPasskeyRepo) GetByCredentialID(credentialID []byte) (*domain.Passkey, error) {
+	for _, passkey := range m.passkeys {
+		if bytes.Equal(passkey.CredentialID, credentialID) {
+			return passkey, nil
+		}
+	}
+	return nil, domain.ErrNoResult
+}
+
+func (m *memoryPasskeyRepo) ListByUser(userID int64) ([]*domain.Passkey, error) {
+	var passkeys []*domain.Passkey
+	for _, passkey := range m.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (m *memoryPasskeyRepo) UpdateSignCount(passkey *domain.Passkey) error {
+	return nil
+}
+
+type memoryPreviousPasswordRepo struct {
+	domain.PreviousPasswordRepo
+}
+
+func (m *memoryPreviousPasswordRepo) Create(password *domain.PreviousPassword) (*domain.PreviousPassword, error) {
+	return password, nil
+}
+
+func (m *memoryPreviousPasswordRepo) DeleteOlder(userID int64, keep int) error {
+	return nil
+}
+
+type memoryUserTokenRepo struct {
+	domain.UserTokenRepo
+}
+
+func (m *memoryUserTokenRepo) Create(token *domain.UserToken) (*domain.UserToken, error) {
+	return token, nil
+}
+
+func (m *memoryUserTokenRepo) DeleteByUser(userID int64, purpose string) error {
+	return nil
+}
+
+const testOrigin = "https://todo.example.com"
+
+func testHasher() *domain.Argon2idHasher {
+	return &domain.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+// hashPassword is the hash of the test hasher
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := testHasher().Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// newTestDomain has the keys, stores and repos the tests use, with the emails kept in memory
+func newTestDomain(t *testing.T) (*domain.Domain, *mailer.Memory) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signing, err := domain.NewSigningKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := domain.NewKeyRing("todo-api", "todo-api", time.Minute, signing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mails := &mailer.Memory{}
+
+	return &domain.Domain{
+		DB: domain.DB{
+			UserRepo:             &memoryUserRepo{},
+			PasskeyRepo:          &memoryPasskeyRepo{},
+			PreviousPasswordRepo: &memoryPreviousPasswordRepo{},
+			UserTokenRepo:        &memoryUserTokenRepo{},
+		},
+		Revocations:   domain.NewMemoryRevocationStore(),
+		LoginAttempts: domain.NewMemoryLoginAttemptStore(),
+		Keys:          keys,
+		Mailer:        mails,
+		AppURL:        testOrigin,
+		// the parameters of the hashes are kept low so the tests run fast
+		PasswordHasher: testHasher(),
+		WebAuthn: &webauthn.RelyingParty{
+			ID:      "todo.example.com",
+			Name:    "Todo",
+			Origins: []string{testOrigin},
+		},
+	}, mails
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// A RevocationStore remembers the access tokens (by jti) that were logged out before they expired
+type RevocationStore interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+// how often the memory store drops the tokens that already expired
+const revocationSweepInterval = time.Minute
+
+// MemoryRevocationStore keeps the revoked tokens in memory until they expire.
+// It's only valid for a single instance, use the postgres store otherwise
+type MemoryRevocationStore struct {
+	mu        sync.Mutex
+	revoked   map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:   make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revoked[jti] = expiresAt
+	m.sweep(time.Now())
+
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	expiresAt, ok := m.revoked[jti]
+	return ok && now.Before(expiresAt), nil
+}
+
+// sweep evicts the expired tokens, at most once per revocationSweepInterval. mu must be held
+func (m *MemoryRevocationStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < revocationSweepInterval {
+		return
+	}
+
+	for jti, expiresAt := range m.revoked {
+		if !now.Before(expiresAt) {
+			delete(m.revoked, jti)
+		}
+	}
+
+	m.lastSweep = now
+}
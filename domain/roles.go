@@ -0,0 +1,23 @@
+package domain
+
+// Roles of the users, admins can use the /admin api
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{RoleUser, RoleAdmin}
+
+func (u *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
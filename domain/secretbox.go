@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// A SecretBox encrypts the secrets we keep at rest (e.g TOTP secrets) with AES-256-GCM
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox takes a 32 bytes key
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// SecretBoxFromEnv reads the base64 key of MFA_ENCRYPTION_KEY, e.g openssl rand -base64 32.
+// Without a key it returns nil and the features that need it are disabled
+func SecretBoxFromEnv() (*SecretBox, error) {
+	encoded := os.Getenv("MFA_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	return NewSecretBox(key)
+}
+
+// Seal returns the base64 of the nonce followed by the ciphertext
+func (b *SecretBox) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *SecretBox) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < b.aead.NonceSize() {
+		return nil, errors.New("sealed secret too short")
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	return b.aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// A Session is a login on a device. It lasts as long as its refresh token family, and the access
+// tokens carry its id (sid) so revoking it logs the device out right away
+type Session struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	FamilyID   string     `json:"-"` // of the refresh tokens
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	DeviceName string     `json:"deviceName"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+
+	Current bool `json:"current" pg:"-"` // the session of the request
+}
+
+func (s *Session) IsOwner(user *User) bool {
+	return s.UserID == user.ID
+}
+
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// SessionClient is where a login comes from
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+const (
+	NotificationNewDevice = "new_device"
+
+	// the last seen time is updated at most this often by the requests of a session
+	sessionTouchInterval = 5 * time.Minute
+)
+
+// startSession records the login. A device the user never logged in from gets a notification,
+// unless it's their first login
+func (d *Domain) startSession(user *User, familyID string, client SessionClient, notifyNewDevice bool) (*Session, error) {
+	deviceName := DeviceName(client.UserAgent)
+
+	known, err := d.DB.SessionRepo.KnownDevices(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := d.DB.SessionRepo.Create(&Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		DeviceName: deviceName,
+		LastSeenAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if notifyNewDevice && len(known) > 0 && !containsString(known, deviceName) {
+		d.notifyNewDevice(user, session)
+	}
+
+	return session, nil
+}
+
+func (d *Domain) notifyNewDevice(user *User, session *Session) {
+	message := fmt.Sprintf("New login from %s (%s)", session.DeviceName, session.IP)
+
+	d.notify(user.ID, Notification{
+		Kind:    NotificationNewDevice,
+		Message: message,
+	})
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "New login to your account",
+		Body: fmt.Sprintf("Hi %s,\n\n%s on %s.\nIf it wasn't you, revoke the session in your account settings and change your password.\n",
+			user.Username, message, session.CreatedAt.In(user.Location()).Format("January 2, 2006 15:04 MST")),
+	})
+}
+
+// sessionOfFamily returns the session of a refresh token. Logins from before the sessions get one
+func (d *Domain) sessionOfFamily(user *User, familyID string, client SessionClient) (*Session, error) {
+	session, err := d.DB.SessionRepo.GetByFamilyID(familyID)
+	if errors.Is(err, ErrNoResult) {
+		return d.startSession(user, familyID, client, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.IsRevoked() || session.UserID != user.ID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session.IP = client.IP
+	session.LastSeenAt = time.Now()
+
+	return session, d.DB.SessionRepo.Touch(session)
+}
+
+// checkSession rejects the access tokens of a revoked session. Tokens from before the sessions have no sid
+func (d *Domain) checkSession(user *User, claims jwt.MapClaims) error {
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return nil
+	}
+
+	session, err := d.DB.SessionRepo.GetByID(int64(sid))
+	if err != nil || session.IsRevoked() || session.UserID != user.ID {
+		return ErrInvalidToken
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = time.Now()
+		if err := d.DB.SessionRepo.Touch(session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SessionIDFromToken is the sid claim of an access token, 0 when it has none
+func SessionIDFromToken(token *jwt.Token) int64 {
+	if token == nil {
+		return 0
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+
+	sid, _ := claims["sid"].(float64)
+	return int64(sid)
+}
+
+// ListSessions returns the sessions that are still valid, current is the one of the request (0 for none)
+func (d *Domain) ListSessions(user *User, current int64) ([]*Session, error) {
+	sessions, err := d.DB.SessionRepo.ListActiveByUser(user.ID, time.Now().Add(-RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == current
+	}
+
+	return sessions, nil
+}
+
+func (d *Domain) GetSessionByID(id int64) (*Session, error) {
+	return d.DB.SessionRepo.GetByID(id)
+}
+
+// RevokeSession logs the device out: its refresh tokens and its access tokens stop working
+func (d *Domain) RevokeSession(session *Session, user *User) error {
+	if err := mustOwn(session, user); err != nil {
+		return err
+	}
+
+	if err := d.DB.SessionRepo.Revoke(session); err != nil {
+		return err
+	}
+
+	return d.DB.RefreshTokenRepo.RevokeFamily(session.FamilyID)
+}
+
+// DeleteExpiredSessions forgets the sessions whose refresh tokens expired. It runs as a background job
+func (d *Domain) DeleteExpiredSessions(now time.Time) error {
+	return d.DB.SessionRepo.DeleteInactive(now.Add(-RefreshTokenTTL))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
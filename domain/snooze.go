@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Snooze presets, resolved in the timezone of the user
+const (
+	SnoozeTonight     = "tonight"      // today at 20:00, or tomorrow if it's already later
+	SnoozeTomorrow    = "tomorrow"     // tomorrow at 09:00
+	SnoozeThisWeekend = "this_weekend" // next saturday at 09:00
+	SnoozeNextWeek    = "next_week"    // next monday at 09:00
+)
+
+var SnoozePresets = []string{SnoozeTonight, SnoozeTomorrow, SnoozeThisWeekend, SnoozeNextWeek}
+
+type SnoozePayload struct {
+	Preset string     `json:"preset"`
+	Until  *time.Time `json:"until"` // exact date, instead of a preset
+}
+
+func (s *SnoozePayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	if s.Until == nil {
+		v.MustBeNotEmpty("preset", s.Preset)
+		v.MustBeOneOf("preset", s.Preset, SnoozePresets)
+	} else if s.Preset != "" {
+		v.errors["preset"] = ErrSnoozePresetAndUntil.Error()
+	}
+
+	return v.IsValid(), v.errors
+}
+
+// resolveSnooze returns when a preset ends. now must be in the user location
+func resolveSnooze(preset string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	at := func(day time.Time, hour int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, day.Location())
+	}
+
+	switch preset {
+	case SnoozeTonight:
+		tonight := at(today, 20)
+		if !tonight.After(now) {
+			return tonight.AddDate(0, 0, 1)
+		}
+		return tonight
+	case SnoozeThisWeekend:
+		days := (int(time.Saturday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return at(today.AddDate(0, 0, days), 9)
+	case SnoozeNextWeek:
+		days := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return at(today.AddDate(0, 0, days), 9)
+	default:
+		return at(today.AddDate(0, 0, 1), 9)
+	}
+}
+
+// SnoozeTodo hides the todo from the default listings until the preset (or the given date) arrives
+func (d *Domain) SnoozeTodo(todo *Todo, payload SnoozePayload, user *User) (*Todo, error) {
+	now := time.Now().In(user.Location())
+
+	until := payload.Until
+	if until == nil {
+		resolved := resolveSnooze(payload.Preset, now)
+		until = &resolved
+	}
+
+	if !until.After(now) {
+		return nil, ErrSnoozeInThePast
+	}
+
+	todo.SnoozedUntil = until
+	todo.UpdatedAt = time.Now()
+
+	return d.DB.TodoRepo.Update(todo)
+}
+
+func (d *Domain) UnsnoozeTodo(todo *Todo) (*Todo, error) {
+	todo.SnoozedUntil = nil
+	todo.UpdatedAt = time.Now()
+
+	return d.DB.TodoRepo.Update(todo)
+}
+
+// WakeSnoozedTodos surfaces the todos whose snooze ended and notifies their owner. It runs as a background job
+func (d *Domain) WakeSnoozedTodos(now time.Time) error {
+	todos, err := d.DB.TodoRepo.ListSnoozed(now)
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range todos {
+		woken, err := d.DB.TodoRepo.Wake(todo.ID, now)
+		if err != nil {
+			return err
+		}
+		if !woken {
+			continue
+		}
+
+		todoID := todo.ID
+		d.notify(todo.UserID, Notification{
+			Kind:    NotificationTodoWokeUp,
+			Message: fmt.Sprintf("%q is back in your list", todo.Title),
+			TodoID:  &todoID,
+		})
+	}
+
+	return nil
+}
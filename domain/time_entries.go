@@ -0,0 +1,218 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// A TimeEntry is a block of time a user worked on a todo. While the timer is running StoppedAt is nil
+type TimeEntry struct {
+	ID        int64      `json:"id"`
+	TodoID    int64      `json:"todoId"`
+	UserID    int64      `json:"userId"`
+	Note      string     `json:"note"`
+	StartedAt time.Time  `json:"startedAt"`
+	StoppedAt *time.Time `json:"stoppedAt"`
+
+	// Only loaded for the timesheet export
+	Todo *Todo `json:"-" pg:"rel:has-one"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (e *TimeEntry) IsRunning() bool {
+	return e.StoppedAt == nil
+}
+
+// Duration of the entry. For a running timer we count until now
+func (e *TimeEntry) Duration(now time.Time) time.Duration {
+	if e.StoppedAt != nil {
+		return e.StoppedAt.Sub(e.StartedAt)
+	}
+
+	return now.Sub(e.StartedAt)
+}
+
+func (e *TimeEntry) IsOwner(user *User) bool {
+	return e.UserID == user.ID
+}
+
+// Entries are always tracked against a todo, so the user must own it (see HaveOwner)
+func mustOwn(subject HaveOwner, user *User) error {
+	if !subject.IsOwner(user) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+func (d *Domain) StartTimer(todo *Todo, user *User) (*TimeEntry, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	running, err := d.DB.TimeEntryRepo.GetRunning(user.ID)
+	if err != nil && !errors.Is(err, ErrNoResult) {
+		return nil, err
+	}
+
+	if running != nil {
+		return nil, ErrTimerAlreadyRunning
+	}
+
+	data := &TimeEntry{
+		TodoID:    todo.ID,
+		UserID:    user.ID,
+		StartedAt: time.Now(),
+	}
+
+	// The database also enforces one running timer per user, in case two requests race each other
+	entry, err := d.DB.TimeEntryRepo.Create(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (d *Domain) StopTimer(todo *Todo, user *User) (*TimeEntry, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	running, err := d.DB.TimeEntryRepo.GetRunning(user.ID)
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, ErrNoRunningTimer
+		}
+		return nil, err
+	}
+
+	if running.TodoID != todo.ID {
+		return nil, ErrNoRunningTimer
+	}
+
+	now := time.Now()
+	running.StoppedAt = &now
+	running.UpdatedAt = now
+
+	entry, err := d.DB.TimeEntryRepo.Update(running)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+type CreateTimeEntryPayload struct {
+	StartedAt time.Time `json:"startedAt"`
+	StoppedAt time.Time `json:"stoppedAt"`
+	Note      string    `json:"note"`
+}
+
+func (c *CreateTimeEntryPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotZero("startedAt", c.StartedAt)
+	v.MustBeNotZero("stoppedAt", c.StoppedAt)
+	v.MustBeAfter("stoppedAt", c.StoppedAt, "startedAt", c.StartedAt)
+
+	return v.IsValid(), v.errors
+}
+
+// CreateTimeEntry adds a manual (already stopped) entry to the todo
+func (d *Domain) CreateTimeEntry(payload CreateTimeEntryPayload, todo *Todo, user *User) (*TimeEntry, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	stoppedAt := payload.StoppedAt
+
+	data := &TimeEntry{
+		TodoID:    todo.ID,
+		UserID:    user.ID,
+		Note:      payload.Note,
+		StartedAt: payload.StartedAt,
+		StoppedAt: &stoppedAt,
+	}
+
+	entry, err := d.DB.TimeEntryRepo.Create(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (d *Domain) ListTimeEntries(todo *Todo, user *User) ([]*TimeEntry, error) {
+	if err := mustOwn(todo, user); err != nil {
+		return nil, err
+	}
+
+	entries, err := d.DB.TimeEntryRepo.ListByTodo(todo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// TimeEntryFilter restricts entries to a user and optionally to [From, To)
+type TimeEntryFilter struct {
+	UserID   int64
+	From     time.Time
+	To       time.Time
+	Location *time.Location // the days of the report are the ones of the user, UTC when nil
+}
+
+type TimeReportGroup string
+
+const (
+	TimeReportByTodo    TimeReportGroup = "todo"
+	TimeReportByProject TimeReportGroup = "project"
+	TimeReportByDay     TimeReportGroup = "day"
+)
+
+func (g TimeReportGroup) IsValid() bool {
+	switch g {
+	case TimeReportByTodo, TimeReportByProject, TimeReportByDay:
+		return true
+	}
+
+	return false
+}
+
+// One row of a time report. Only the fields of the chosen grouping are set
+type TimeReportRow struct {
+	TodoID    *int64 `json:"todoId,omitempty"`
+	TodoTitle string `json:"todoTitle,omitempty"`
+	ProjectID *int64 `json:"projectId,omitempty"`
+	Day       string `json:"day,omitempty"`
+	Seconds   int64  `json:"seconds"`
+}
+
+func (d *Domain) TimeReport(user *User, groupBy TimeReportGroup, from, to time.Time) ([]*TimeReportRow, error) {
+	if !groupBy.IsValid() {
+		return nil, ErrInvalidReportGroup
+	}
+
+	filter := TimeEntryFilter{UserID: user.ID, From: from, To: to, Location: user.Location()}
+
+	rows, err := d.DB.TimeEntryRepo.Totals(filter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Timesheet returns the user entries (with their todo loaded) ordered by start time, ready to export
+func (d *Domain) Timesheet(user *User, from, to time.Time) ([]*TimeEntry, error) {
+	entries, err := d.DB.TimeEntryRepo.ListByUser(TimeEntryFilter{UserID: user.ID, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -1,19 +1,48 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"todo/quickadd"
+)
 
 type Todo struct {
-	ID        int64  `json:"id"`
-	Title     string `json:"title"`
-	Completed bool   `json:"completed", pg:",use_zero"` // pg doesnt like when we set false a TODO, so we add another tag
-	UserID    int64  `json:"userId"`
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`                   // state of the todo in its workflow
+	Completed  bool   `json:"completed" pg:",use_zero"` // derived from Status, kept for backward compatibility
+	UserID     int64  `json:"userId"`                   // the creator of the todo
+	AssigneeID *int64 `json:"assigneeId"`               // set once an assignment is accepted
+	ProjectID  *int64 `json:"projectId"`
+
+	Tags       []string   `json:"tags" pg:",array"`
+	Priority   string     `json:"priority"`
+	DueAt      *time.Time `json:"dueAt"`
+	Recurrence string     `json:"recurrence"` // RFC 5545 RRULE, e.g FREQ=WEEKLY;BYDAY=MO
+
+	CompletedAt  *time.Time `json:"completedAt"`
+	ArchivedAt   *time.Time `json:"archivedAt"` // archived todos are hidden from the listings by default
+	UnarchivedAt *time.Time `json:"-"`
+	SnoozedUntil *time.Time `json:"snoozedUntil"` // hidden from the listings by default until then
+
+	// Filled from the dependency graph, not stored in the todos table
+	Blocked   bool    `json:"blocked" pg:"-"`
+	BlockedBy []int64 `json:"blockedBy" pg:"-"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// Todo priorities. An empty priority means none
+var Priorities = []string{"low", "medium", "high"}
+
 type CreateTodoPayload struct {
-	Title string `json:"title"`
+	Title      string     `json:"title"`
+	ProjectID  *int64     `json:"projectId"`
+	Tags       []string   `json:"tags"`
+	Priority   string     `json:"priority"`
+	DueAt      *time.Time `json:"dueAt"`
+	Recurrence string     `json:"recurrence"`
 }
 
 func (c *CreateTodoPayload) IsValid() (bool, map[string]string) {
@@ -22,16 +51,47 @@ func (c *CreateTodoPayload) IsValid() (bool, map[string]string) {
 	v.MustBeNotEmpty("title", c.Title)
 	v.MustBeLongerThan("title", c.Title, 3)
 
+	if c.Priority != "" {
+		v.MustBeOneOf("priority", c.Priority, Priorities)
+	}
+
+	for _, tag := range c.Tags {
+		v.MustBeNotEmpty("tags", tag)
+	}
+
 	return v.IsValid(), v.errors
 }
 
 // We build the function interface for the Todo, so domain is also a Todo type
 func (d *Domain) CreateTodo(payload CreateTodoPayload, user *User) (*Todo, error) {
 
+	workflow := DefaultWorkflow
+
+	// A todo can only be added to a project of the same user
+	if payload.ProjectID != nil {
+		project, err := d.DB.ProjectRepo.GetByID(*payload.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !project.IsOwner(user) {
+			return nil, ErrNoResult
+		}
+
+		workflow = project.GetWorkflow()
+	}
+
 	data := &Todo{
 		Title:     payload.Title,
-		Completed: false,
+		Status:    workflow.Initial,
+		Completed: workflow.IsDone(workflow.Initial),
 		UserID:    user.ID,
+		ProjectID: payload.ProjectID,
+
+		Tags:       payload.Tags,
+		Priority:   payload.Priority,
+		DueAt:      payload.DueAt,
+		Recurrence: payload.Recurrence,
 	}
 
 	todo, err := d.DB.TodoRepo.Create(data)
@@ -39,19 +99,107 @@ func (d *Domain) CreateTodo(payload CreateTodoPayload, user *User) (*Todo, error
 		return nil, err
 	}
 
+	d.notifyMentions(user, todo, todo.Title, "")
+
 	return todo, nil
 }
 
+type QuickAddPayload struct {
+	Text string `json:"text"`
+}
+
+func (q *QuickAddPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("text", q.Text)
+
+	return v.IsValid(), v.errors
+}
+
+// QuickAddTodo parses free text like "Pay rent every 1st #finance !high tomorrow 9am" and creates the todo.
+// It also returns what was understood, so the client can show it.
+func (d *Domain) QuickAddTodo(payload QuickAddPayload, user *User) (*Todo, *quickadd.Result, error) {
+	parsed := quickadd.Parse(payload.Text, time.Now().In(user.Location()))
+
+	create := CreateTodoPayload{
+		Title:      parsed.Title,
+		Tags:       parsed.Tags,
+		Priority:   parsed.Priority,
+		DueAt:      parsed.DueAt,
+		Recurrence: parsed.Recurrence,
+	}
+
+	if isValid, errs := create.IsValid(); !isValid {
+		return nil, &parsed, ErrValidation{Errors: errs}
+	}
+
+	todo, err := d.CreateTodo(create, user)
+	if err != nil {
+		return nil, &parsed, err
+	}
+
+	return todo, &parsed, nil
+}
+
 func (d *Domain) GetTodoByID(id int64) (*Todo, error) {
 	todo, err := d.DB.TodoRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	edges, err := d.DB.DependencyRepo.ListByTodo(todo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	setBlocked([]*Todo{todo}, edges)
+
 	return todo, nil
 }
 
-func (d *Domain) DeleteTodo(todo *Todo) error {
+// TodoFilter narrows the todos listing. Empty fields are not applied
+type TodoFilter struct {
+	UserID    int64
+	View      string // one of the TodoViews, by default both
+	ProjectID *int64
+	Status    string
+	Completed *bool
+
+	IncludeArchived bool
+	OnlyArchived    bool
+	IncludeSnoozed  bool
+}
+
+func (d *Domain) ListTodos(user *User, filter TodoFilter) ([]*Todo, error) {
+	filter.UserID = user.ID
+
+	if filter.View != TodoViewAll {
+		v := NewValidator()
+		if !v.MustBeOneOf("view", filter.View, TodoViews) {
+			return nil, ErrValidation{Errors: v.errors}
+		}
+	}
+
+	todos, err := d.DB.TodoRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := d.DB.DependencyRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	setBlocked(todos, edges)
+
+	return todos, nil
+}
+
+func (d *Domain) DeleteTodo(todo *Todo, user *User) error {
+	if !todo.IsCreator(user) {
+		return ErrForbidden
+	}
+
 	err := d.DB.TodoRepo.Delete(todo)
 	if err != nil {
 		return err
@@ -63,28 +211,59 @@ func (d *Domain) DeleteTodo(todo *Todo) error {
 type UpdateTodoPayload struct {
 	Title     *string `json:"title"`     // Since the title already exists, we take the pointer
 	Completed *bool   `json:"completed"` // The same for completed
+	Status    *string `json:"status"`    // moves the todo in its workflow. Completed is kept for older clients
 }
 
 func (u *UpdateTodoPayload) IsValid() (bool, map[string]string) {
 	v := NewValidator()
 
-	if *u.Title != "" {
+	if u.Title != nil && *u.Title != "" {
 		v.MustBeLongerThan("title", *u.Title, 3)
 	}
 
 	return v.IsValid(), v.errors
 }
 
-func (d *Domain) UpdateTodo(todo *Todo, payload UpdateTodoPayload) (*Todo, error) {
+func (d *Domain) UpdateTodo(todo *Todo, payload UpdateTodoPayload, user *User) (*Todo, error) {
 
 	didUpdate := false
+	previousTitle := todo.Title
 
-	if *payload.Title != "" {
+	if payload.Title != nil && *payload.Title != "" {
 		todo.Title = *payload.Title
+		didUpdate = true
 	}
 
-	if payload.Completed != nil {
-		todo.Completed = *payload.Completed
+	if payload.Status != nil || payload.Completed != nil {
+		workflow, err := d.workflowFor(todo)
+		if err != nil {
+			return nil, err
+		}
+
+		status := todo.Status
+
+		if payload.Status != nil {
+			status = *payload.Status
+		} else if *payload.Completed != todo.Completed {
+			// translate the old flag to the first done (or not done) state we can move to
+			next, ok := workflow.nextState(todo.Status, *payload.Completed)
+			if !ok {
+				return nil, ErrNoTransition{from: todo.Status, done: *payload.Completed}
+			}
+			status = next
+		}
+
+		// a todo can't be completed while other todos are blocking it
+		if workflow.IsDone(status) && !todo.Completed {
+			if err := d.ensureNotBlocked(todo); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := workflow.moveTodo(todo, status); err != nil {
+			return nil, err
+		}
+		didUpdate = true
 	}
 
 	if didUpdate {
@@ -97,9 +276,22 @@ func (d *Domain) UpdateTodo(todo *Todo, payload UpdateTodoPayload) (*Todo, error
 		return nil, err
 	}
 
+	// only the users mentioned for the first time
+	d.notifyMentions(user, todo, todo.Title, previousTitle)
+
 	return todo, nil
 }
 
+func (t *Todo) IsArchived() bool {
+	return t.ArchivedAt != nil
+}
+
+// IsOwner is true for the creator and the assignee, who both have edit rights
 func (t *Todo) IsOwner(user *User) bool {
+	return t.IsCreator(user) || (t.AssigneeID != nil && *t.AssigneeID == user.ID)
+}
+
+// IsCreator is true only for the user that created the todo. Deleting and assigning are reserved to them
+func (t *Todo) IsCreator(user *User) bool {
 	return t.UserID == user.ID
 }
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute    // short lived, the client refreshes it with the refresh token
+	RefreshTokenTTL = 30 * 24 * time.Hour // each rotation gives a new refresh token valid for this long
+)
+
+// A RefreshToken is opaque for the client. We only store its hash, so a leaked table can't be used to log in
+type RefreshToken struct {
+	ID        int64
+	FamilyID  string
+	TokenHash string
+	UserID    int64
+
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r *RefreshTokenPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("refreshToken", r.RefreshToken)
+
+	return v.IsValid(), v.errors
+}
+
+// randomToken returns n random bytes encoded for URLs
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is enough for random tokens (unlike passwords they can't be guessed from a dictionary)
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueTokens gives a new access token and starts a new refresh token family and session (i.e a login)
+func (d *Domain) IssueTokens(user *User, client SessionClient) (*JWTToken, error) {
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := d.startSession(user, familyID, client, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.issueTokens(user, session)
+}
+
+func (d *Domain) issueTokens(user *User, session *Session) (*JWTToken, error) {
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	token, err := user.GenToken(d.Keys, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := d.DB.RefreshTokenRepo.Create(&RefreshToken{
+		FamilyID:  session.FamilyID,
+		TokenHash: hashToken(refreshToken),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token.RefreshToken = refreshToken
+	token.RefreshExpiresAt = refresh.ExpiresAt
+
+	return token, nil
+}
+
+// RefreshTokens rotates the refresh token: the one received is used up and a new one is returned.
+// Receiving an already used token means it was stolen (either the thief or the user used it before),
+// so the whole family is revoked and both have to log in again.
+func (d *Domain) RefreshTokens(payload RefreshTokenPayload, client SessionClient) (*User, *JWTToken, error) {
+	current, err := d.DB.RefreshTokenRepo.GetByHash(hashToken(payload.RefreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		if err := d.DB.RefreshTokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// two requests with the same token can race, only one of them marks it as used
+	used, err := d.DB.RefreshTokenRepo.MarkUsed(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !used {
+		if err := d.DB.RefreshTokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	user, err := d.DB.UserRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// a revoked session can't come back with its refresh token
+	session, err := d.sessionOfFamily(user, current.FamilyID, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := d.issueTokens(user, session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, token, nil
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Purposes of the single use tokens we send to the users
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeMFARecovery       = "mfa_recovery"
+	TokenPurposeEmailChange       = "email_change"
+)
+
+// A UserToken is a single use token we send to the user (e.g by email). Only its hash is stored
+type UserToken struct {
+	ID        int64
+	Purpose   string
+	TokenHash string
+	Data      string // what the token was issued for, e.g the email to verify
+	UserID    int64
+
+	ExpiresAt *time.Time // nil never expires
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// newUserToken returns the token to send to the user. Older tokens with the same purpose stop working
+func (d *Domain) newUserToken(user *User, purpose, data string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.DB.UserTokenRepo.DeleteByUser(user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+
+	_, err = d.DB.UserTokenRepo.Create(&UserToken{
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Data:      data,
+		UserID:    user.ID,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// findUserToken checks the token without using it up, e.g. to validate the request first.
+// Unknown, used and expired tokens are all ErrInvalidToken
+func (d *Domain) findUserToken(purpose, token string) (*UserToken, error) {
+	userToken, err := d.DB.UserTokenRepo.Get(purpose, hashToken(token))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return userToken, nil
+}
+
+// consumeUserToken uses up the token. Unknown, used and expired tokens are all ErrInvalidToken
+func (d *Domain) consumeUserToken(purpose, token string) (*UserToken, error) {
+	userToken, err := d.DB.UserTokenRepo.Consume(purpose, hashToken(token))
+	if err != nil {
+		if errors.Is(err, ErrNoResult) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return userToken, nil
+}
@@ -1,13 +1,9 @@
 package domain
 
 import (
-	"fmt"
 	"time"
 
-	"os"
-
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // The first step for auth is to define the User struct.
@@ -16,10 +12,29 @@ import (
 // NOTE: struct tags to control how this information is assigned to the fields of a struct. Struct tags are small pieces of metadata attached to fields of a struct that provide instructions to other Go code that works with the struct.
 // Golang will ignore this unles use the encoding/json package.
 type User struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"-"`
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName" pg:",use_zero"`
+	Email       string `json:"email"`
+	Password    string `json:"-"`
+	Timezone    string `json:"timezone"` // IANA name, relative dates of the user are resolved in it
+
+	// UsernameKey and EmailKey of the username and email, unique. The UserRepo sets them
+	UsernameKey string `json:"-"`
+	EmailKey    string `json:"-"`
+
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+
+	Role       string     `json:"role"` // one of the Roles, see roles.go
+	DisabledAt *time.Time `json:"disabledAt"`
+
+	// two-factor, see mfa.go. The secret is encrypted
+	MFASecret    string     `json:"-" pg:"mfa_secret"`
+	MFAEnabledAt *time.Time `json:"mfaEnabledAt" pg:"mfa_enabled_at"`
+	MFALastStep  int64      `json:"-" pg:"mfa_last_step,use_zero"`
+
+	// access tokens carry the generation they were issued with, bumping it logs the user out everywhere
+	TokenGeneration int64 `json:"-" pg:",use_zero"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
@@ -28,6 +43,10 @@ type User struct {
 type JWTToken struct {
 	AccessToken string    `json:"accessToken"`
 	ExpiresAt   time.Time `json:"expiresAt"`
+
+	// opaque token to get a new access token from /users/token/refresh, see tokens.go
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
 }
 
 // JWT: JSON Web Token - is used to securely transmit information between parties as a JSON object
@@ -38,19 +57,25 @@ type JWTToken struct {
 // 2. When the authorization is granted, the authorization server returns an access token to the application.
 // 3. The application uses the access token to access a protected resource (like an API).
 
-func (u *User) GenToken() (*JWTToken, error) {
-	jwtToken := jwt.New(jwt.GetSigningMethod("HS256"))
+// GenToken signs an access token of the session
+func (u *User) GenToken(keys *KeyRing, sessionID int64) (*JWTToken, error) {
+	expiresAt := time.Now().Add(AccessTokenTTL)
 
-	expiresAt := time.Now().Add(time.Hour * 24 * 7) // valid for 1 week
+	// jti identifies this token, so it can be revoked on logout
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
 
-	// the "body" of our token
-	jwtToken.Claims = jwt.MapClaims{
+	// the "body" of our token. The key ring adds iss, aud, iat and nbf
+	accessToken, err := keys.Sign(jwt.MapClaims{
 		"id":  u.ID,
 		"exp": expiresAt.Unix(),
-	}
-
-	//
-	accessToken, err := jwtToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
+		"jti": jti,
+		"gen": u.TokenGeneration,
+		"typ": TokenTypeAccess,
+		"sid": sessionID,
+	})
 
 	if err != nil {
 		return nil, err
@@ -62,13 +87,14 @@ func (u *User) GenToken() (*JWTToken, error) {
 	}, nil
 }
 
-func (u *User) checkPassword(password string) error {
-	bytePassword := []byte(password)
-	byteHashedPassword := []byte(u.Password)
+// Location of the user timezone, UTC if it's not set or unknown
+func (u *User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil || u.Timezone == "" {
+		return time.UTC
+	}
 
-	fmt.Println(password)
-	fmt.Println(u.Password)
-	return bcrypt.CompareHashAndPassword(byteHashedPassword, bytePassword)
+	return loc
 }
 
 func (d *Domain) GetUserByID(id int64) (*User, error) {
@@ -1,6 +1,9 @@
 package domain
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
 
 // define a global emailRegExp for validation (disclaimer: don't try to write this for youself!)
 var emailRegexp = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
@@ -64,6 +67,74 @@ func (v *Validator) MustBeValidEmail(field, email string) bool {
 	return true
 }
 
+func (v *Validator) MustBeNotZero(field string, value time.Time) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if value.IsZero() {
+		v.errors[field] = ErrIsRequired{field: field}.Error()
+		return false
+	}
+
+	return true
+}
+
+// MustBeAfter checks that value comes after match (e.g, a stop time after the start time)
+func (v *Validator) MustBeAfter(field string, value time.Time, matchField string, match time.Time) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if !value.After(match) {
+		v.errors[field] = ErrMustBeAfter{matchField}.Error()
+		return false
+	}
+
+	return true
+}
+
+func (v *Validator) MustBeAtLeast(field string, value, min int) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if value < min {
+		v.errors[field] = ErrMustBeAtLeast{min}.Error()
+		return false
+	}
+
+	return true
+}
+
+func (v *Validator) MustBeOneOf(field, value string, options []string) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+
+	v.errors[field] = ErrMustBeOneOf{options}.Error()
+	return false
+}
+
+func (v *Validator) MustBeValidTimezone(field, value string) bool {
+	if _, ok := v.errors[field]; ok {
+		return false
+	}
+
+	if _, err := time.LoadLocation(value); err != nil {
+		v.errors[field] = ErrTimezoneBadFormat.Error()
+		return false
+	}
+
+	return true
+}
+
 type ElementMatcher struct {
 	field string
 	value string
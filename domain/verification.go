@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const EmailVerificationTTL = 24 * time.Hour
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// SendVerificationEmail sends a new verification link to the email of the user
+func (d *Domain) SendVerificationEmail(user *User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := d.newUserToken(user, TokenPurposeEmailVerification, user.Email, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	d.sendMail(Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nconfirm your email address by opening %s\n\nThe link is valid for %v.\n",
+			user.Username, d.appLink("/verify-email", token), EmailVerificationTTL),
+	})
+
+	return nil
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token"`
+}
+
+func (v *VerifyEmailPayload) IsValid() (bool, map[string]string) {
+	validator := NewValidator()
+
+	validator.MustBeNotEmpty("token", v.Token)
+
+	return validator.IsValid(), validator.errors
+}
+
+func (d *Domain) VerifyEmail(payload VerifyEmailPayload) (*User, error) {
+	token, err := d.consumeUserToken(TokenPurposeEmailVerification, payload.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := d.DB.UserRepo.GetByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token verifies the address it was sent to, not whatever the user has now
+	if token.Data != user.Email {
+		return nil, ErrInvalidToken
+	}
+
+	if user.IsEmailVerified() {
+		return user, nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+
+	user, err = d.DB.UserRepo.MarkEmailVerified(user)
+	if errors.Is(err, ErrNoResult) {
+		return nil, ErrInvalidToken
+	}
+
+	return user, err
+}
@@ -0,0 +1,187 @@
+package domain
+
+import "time"
+
+// A Workflow is the set of states a todo of a project goes through, e.g "backlog -> in progress -> review -> done".
+// It is stored as JSON together with the project.
+type Workflow struct {
+	Initial     string               `json:"initial"`
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+type WorkflowState struct {
+	Name string `json:"name"`
+	Done bool   `json:"done"` // todos in this state are completed
+}
+
+type WorkflowTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DefaultWorkflow is used by todos without a project (or a project without workflow).
+// It behaves like the old completed flag.
+var DefaultWorkflow = &Workflow{
+	Initial: "todo",
+	States: []WorkflowState{
+		{Name: "todo"},
+		{Name: "done", Done: true},
+	},
+	Transitions: []WorkflowTransition{
+		{From: "todo", To: "done"},
+		{From: "done", To: "todo"},
+	},
+}
+
+func (w *Workflow) state(name string) (WorkflowState, bool) {
+	for _, s := range w.States {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return WorkflowState{}, false
+}
+
+func (w *Workflow) HasState(name string) bool {
+	_, ok := w.state(name)
+	return ok
+}
+
+func (w *Workflow) IsDone(name string) bool {
+	s, _ := w.state(name)
+	return s.Done
+}
+
+// CanTransition reports if a todo can move from one state to another.
+// A todo left in a state that the workflow no longer has can move to any state.
+func (w *Workflow) CanTransition(from, to string) bool {
+	if !w.HasState(to) {
+		return false
+	}
+
+	if !w.HasState(from) {
+		return true
+	}
+
+	for _, t := range w.Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextState finds the first state reachable from the current one that is (or is not) done.
+// We need it so clients that still send "completed" keep working.
+func (w *Workflow) nextState(from string, done bool) (string, bool) {
+	for _, s := range w.States {
+		if s.Done == done && s.Name != from && w.CanTransition(from, s.Name) {
+			return s.Name, true
+		}
+	}
+
+	return "", false
+}
+
+type WorkflowPayload Workflow
+
+func (p *WorkflowPayload) IsValid() (bool, map[string]string) {
+	v := NewValidator()
+
+	v.MustBeNotEmpty("initial", p.Initial)
+
+	names := make(map[string]bool)
+	hasDone := false
+
+	for _, s := range p.States {
+		if !v.MustBeNotEmpty("states", s.Name) {
+			break
+		}
+
+		if names[s.Name] {
+			v.errors["states"] = ErrDuplicated{s.Name}.Error()
+			break
+		}
+
+		names[s.Name] = true
+		hasDone = hasDone || s.Done
+	}
+
+	if len(p.States) == 0 {
+		v.errors["states"] = ErrIsRequired{field: "states"}.Error()
+	} else if !hasDone {
+		v.errors["states"] = ErrWorkflowWithoutDoneState.Error()
+	}
+
+	if p.Initial != "" && len(names) > 0 && !names[p.Initial] {
+		v.errors["initial"] = ErrUnknownState{p.Initial}.Error()
+	}
+
+	for _, t := range p.Transitions {
+		if !names[t.From] {
+			v.errors["transitions"] = ErrUnknownState{t.From}.Error()
+			break
+		}
+
+		if !names[t.To] {
+			v.errors["transitions"] = ErrUnknownState{t.To}.Error()
+			break
+		}
+	}
+
+	return v.IsValid(), v.errors
+}
+
+func (d *Domain) SetProjectWorkflow(project *Project, payload WorkflowPayload) (*Project, error) {
+	workflow := Workflow(payload)
+	project.Workflow = &workflow
+
+	project, err := d.DB.ProjectRepo.Update(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// workflowFor returns the workflow that applies to the todo
+func (d *Domain) workflowFor(todo *Todo) (*Workflow, error) {
+	if todo.ProjectID == nil {
+		return DefaultWorkflow, nil
+	}
+
+	project, err := d.DB.ProjectRepo.GetByID(*todo.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return project.GetWorkflow(), nil
+}
+
+// moveTodo changes the status of the todo along an allowed transition, keeping Completed in sync
+func (w *Workflow) moveTodo(todo *Todo, status string) error {
+	if status == todo.Status {
+		return nil
+	}
+
+	if !w.CanTransition(todo.Status, status) {
+		return ErrInvalidTransition{from: todo.Status, to: status}
+	}
+
+	wasCompleted := todo.Completed
+
+	todo.Status = status
+	todo.Completed = w.IsDone(status)
+
+	if todo.Completed && !wasCompleted {
+		now := time.Now()
+		todo.CompletedAt = &now
+	} else if !todo.Completed {
+		todo.CompletedAt = nil
+	}
+
+	return nil
+}
@@ -10,4 +10,6 @@ require (
 	github.com/lib/pq v1.8.0 // indirect
 	golang.org/x/crypto v0.0.0-20201012173705-84dcc777aaee
 	golang.org/x/net v0.0.0-20201010224723-4f7140c49acb
+	golang.org/x/text v0.3.3
+	rsc.io/qr v0.2.0
 )
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// The export runs in the background, the client polls the job until it's done
+func (s *Server) requestDataExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.domain.RequestDataExport(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, job, http.StatusAccepted)
+	}
+}
+
+// The account is deleted after a grace period, see cancelAccountDeletion
+func (s *Server) deleteAccount() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.DeleteAccountPayload)
+
+		job, err := s.domain.RequestAccountDeletion(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, job, http.StatusAccepted)
+	}, (*domain.DeleteAccountPayload)(nil))
+}
+
+func (s *Server) cancelAccountDeletion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.domain.CancelAccountDeletion(s.currentUserFromCTX(r))
+		if err != nil {
+			if errors.Is(err, domain.ErrNoResult) {
+				jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusNotFound)
+				return
+			}
+
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, job, http.StatusOK)
+	}
+}
+
+func (s *Server) listAccountJobs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobs, err := s.domain.ListAccountJobs(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, jobs, http.StatusOK)
+	}
+}
+
+func (s *Server) getAccountJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.accountJobFromCTX(r), http.StatusOK)
+	}
+}
+
+func (s *Server) downloadExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		file, err := s.domain.OpenExport(s.accountJobFromCTX(r), s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="export.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		io.Copy(w, file)
+	}
+}
+
+func (s *Server) accountJobCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		job, err := s.domain.GetAccountJobByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "accountJob", job)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) accountJobFromCTX(r *http.Request) *domain.AccountJob {
+	job := r.Context().Value("accountJob").(*domain.AccountJob)
+	return job
+}
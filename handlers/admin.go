@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// Admin api, every handler goes through the domain so the action is audited
+
+// searchUsers reads the optional ?q=&role=&disabled=&offset= parameters
+func (s *Server) searchUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := domain.UserFilter{
+			Query: query.Get("q"),
+			Role:  query.Get("role"),
+		}
+
+		if v := query.Get("disabled"); v != "" {
+			disabled, err := strconv.ParseBool(v)
+			if err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+			filter.Disabled = &disabled
+		}
+
+		offset, err := offsetFromQuery(r)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+		filter.Offset = offset
+
+		users, err := s.domain.AdminSearchUsers(s.currentUserFromCTX(r), filter)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, users, http.StatusOK)
+	}
+}
+
+func (s *Server) adminGetUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.domain.AdminGetUser(s.currentUserFromCTX(r), s.targetUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}
+}
+
+// Same filters as the listing of the user
+func (s *Server) adminListUserTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := todoFilterFromQuery(r)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		todos, err := s.domain.AdminListUserTodos(s.currentUserFromCTX(r), s.targetUserFromCTX(r), filter)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todos, http.StatusOK)
+	}
+}
+
+func (s *Server) disableUser() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.DisableUserPayload)
+
+		user, err := s.domain.AdminDisableUser(s.currentUserFromCTX(r), s.targetUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}, (*domain.DisableUserPayload)(nil))
+}
+
+func (s *Server) enableUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.domain.AdminEnableUser(s.currentUserFromCTX(r), s.targetUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}
+}
+
+func (s *Server) forcePasswordReset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.domain.AdminForcePasswordReset(s.currentUserFromCTX(r), s.targetUserFromCTX(r)); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusAccepted)
+	}
+}
+
+// listAuditLogs reads the optional ?actorId=&userId=&action=&offset= parameters
+func (s *Server) listAuditLogs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := domain.AuditLogFilter{Action: query.Get("action")}
+
+		if v := query.Get("actorId"); v != "" {
+			actorID, err := strconv.ParseInt(v, 0, 0)
+			if err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+			filter.ActorID = &actorID
+		}
+
+		if v := query.Get("userId"); v != "" {
+			userID, err := strconv.ParseInt(v, 0, 0)
+			if err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+			filter.TargetUserID = &userID
+		}
+
+		offset, err := offsetFromQuery(r)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+		filter.Offset = offset
+
+		entries, err := s.domain.AdminListAuditLogs(s.currentUserFromCTX(r), filter)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, entries, http.StatusOK)
+	}
+}
+
+func offsetFromQuery(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("offset")
+	if v == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(v)
+	if err != nil || offset < 0 {
+		return 0, errors.New("offset must be a positive number")
+	}
+
+	return offset, nil
+}
+
+// the user of the url, the current user is the admin
+func (s *Server) targetUserCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		user, err := s.domain.GetUserByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "targetUser", user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) targetUserFromCTX(r *http.Request) *domain.User {
+	user := r.Context().Value("targetUser").(*domain.User)
+	return user
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"todo/domain"
+)
+
+func (s *Server) listArchivedTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := todoFilterFromQuery(r)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		todos, err := s.domain.ListArchivedTodos(s.currentUserFromCTX(r), filter)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todos, http.StatusOK)
+	}
+}
+
+func (s *Server) archiveTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, err := s.domain.ArchiveTodo(s.todoFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todo, http.StatusOK)
+	}
+}
+
+func (s *Server) unarchiveTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, err := s.domain.UnarchiveTodo(s.todoFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todo, http.StatusOK)
+	}
+}
+
+func (s *Server) getArchiveRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule, err := s.domain.GetArchiveRule(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, rule, http.StatusOK)
+	}
+}
+
+func (s *Server) setArchiveRule() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ArchiveRulePayload)
+
+		rule, err := s.domain.SetArchiveRule(payload, s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, rule, http.StatusOK)
+
+	}, (*domain.ArchiveRulePayload)(nil))
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) assignTodo() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.AssignTodoPayload)
+
+		assignment, err := s.domain.AssignTodo(s.todoFromCTX(r), payload, s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, assignment, http.StatusCreated)
+
+	}, (*domain.AssignTodoPayload)(nil))
+}
+
+func (s *Server) unassignTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, err := s.domain.UnassignTodo(s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todo, http.StatusOK)
+	}
+}
+
+func (s *Server) listPendingAssignments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignments, err := s.domain.ListPendingAssignments(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, assignments, http.StatusOK)
+	}
+}
+
+func (s *Server) acceptAssignment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignment, err := s.domain.AcceptAssignment(s.assignmentFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, assignment, http.StatusOK)
+	}
+}
+
+func (s *Server) declineAssignment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignment, err := s.domain.DeclineAssignment(s.assignmentFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, assignment, http.StatusOK)
+	}
+}
+
+func (s *Server) assignmentCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		assignment, err := s.domain.GetAssignmentByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "assignment", assignment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) assignmentFromCTX(r *http.Request) *domain.Assignment {
+	assignment := r.Context().Value("assignment").(*domain.Assignment)
+	return assignment
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"todo/domain"
+)
+
+func (s *Server) createComment() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.CreateCommentPayload)
+
+		comment, err := s.domain.CreateComment(payload, s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, comment, http.StatusCreated)
+
+	}, (*domain.CreateCommentPayload)(nil))
+}
+
+func (s *Server) listComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		comments, err := s.domain.ListComments(s.todoFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, comments, http.StatusOK)
+	}
+}
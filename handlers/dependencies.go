@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) listDependencies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		edges, err := s.domain.ListDependencies(s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, edges, http.StatusOK)
+	}
+}
+
+func (s *Server) addDependency() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.AddDependencyPayload)
+
+		dependency, err := s.domain.AddDependency(s.todoFromCTX(r), payload, s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, dependency, http.StatusCreated)
+
+	}, (*domain.AddDependencyPayload)(nil))
+}
+
+func (s *Server) removeDependency() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockedByID, err := strconv.ParseInt(chi.URLParam(r, "blockedById"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		err = s.domain.RemoveDependency(s.todoFromCTX(r), blockedByID, s.currentUserFromCTX(r))
+
+		if errors.Is(err, domain.ErrNoResult) {
+			jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"todo/domain"
+
 	"github.com/go-chi/chi"
 )
 
 func (s *Server) setupEndpoints(r *chi.Mux) {
 	// This function allocates all endpoints from our rest api
 	// the client will have to write the pattern to get something
+	// public keys to verify our access tokens
+	r.Get("/.well-known/jwks.json", s.jwks())
+
 	r.Route("/api/v1", func(r chi.Router) {
 		// this function adds another child route to the main api
 		// we set it as users
@@ -18,28 +23,243 @@ func (s *Server) setupEndpoints(r *chi.Mux) {
 			r.Post("/register", s.registerUser())
 
 			r.Post("/login", s.loginUser())
+			r.Post("/login/mfa", s.loginMFA())
+
+			// passwordless login with a passkey (WebAuthn assertion)
+			r.Post("/passkeys/login/begin", s.beginPasskeyLogin())
+			r.Post("/passkeys/login/finish", s.finishPasskeyLogin())
+
+			// social login with the OpenID providers
+			r.Get("/oidc/providers", s.listOIDCProviders())
+			r.Post("/oidc/{provider}/authorize", s.beginOIDCLogin())
+			r.Post("/oidc/{provider}/callback", s.oidcCallback())
+
+			r.Post("/token/refresh", s.refreshToken())
+
+			// the token comes from the email sent on registration
+			r.Post("/verify-email", s.verifyEmail())
+
+			r.Post("/password/forgot", s.forgotPassword())
+			r.Post("/password/reset", s.resetPassword())
+
+			// the token comes from the email sent to the new address
+			r.Post("/email/confirm", s.confirmEmailChange())
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.withUser)
+				r.Use(s.withoutPersonalAccessToken)
+				r.Post("/logout", s.logoutUser())
+				r.Post("/logout-all", s.logoutEverywhere())
+				r.Post("/verify-email/resend", s.resendVerificationEmail())
+
+				r.Get("/me", s.getCurrentUser())
+				r.Patch("/me", s.updateCurrentUser())
+				r.Post("/me/password", s.changePassword())
+				r.Post("/me/email", s.changeEmail())
+
+				// GDPR: export of the data and deletion of the account, both run in the background
+				r.Post("/me/export", s.requestDataExport())
+				r.Delete("/me", s.deleteAccount())
+				r.Delete("/me/deletion", s.cancelAccountDeletion())
+
+				r.Route("/me/jobs", func(r chi.Router) {
+					r.Get("/", s.listAccountJobs())
+
+					r.Route("/{id}", func(r chi.Router) {
+						r.Use(s.accountJobCtx)
+						r.Use(s.withOwner("accountJob"))
+
+						r.Get("/", s.getAccountJob())
+						r.Get("/download", s.downloadExport())
+					})
+				})
+
+				// two-factor authentication
+				r.Post("/me/mfa/totp", s.beginTOTPEnrollment())
+				r.Get("/me/mfa/totp/qr.png", s.totpEnrollmentQR())
+				r.Post("/me/mfa/totp/confirm", s.confirmTOTPEnrollment())
+				r.Delete("/me/mfa/totp", s.disableTOTP())
+				r.Post("/me/mfa/recovery-codes", s.regenerateRecoveryCodes())
+
+				r.Route("/me/passkeys", func(r chi.Router) {
+					r.Get("/", s.listPasskeys())
+					r.Post("/register/begin", s.beginPasskeyRegistration())
+					r.Post("/register/finish", s.finishPasskeyRegistration())
+
+					r.With(s.passkeyCtx, s.withOwner("passkey")).Delete("/{id}", s.deletePasskey())
+				})
+
+				// accounts of the OpenID providers linked to the user
+				r.Route("/me/identities", func(r chi.Router) {
+					r.Get("/", s.listIdentities())
+					r.Post("/", s.linkIdentity())
+					r.Post("/{provider}/callback", s.linkIdentityCallback())
+
+					r.With(s.identityCtx, s.withOwner("identity")).Delete("/{id}", s.unlinkIdentity())
+				})
+
+				// the devices the user is logged in from
+				r.Route("/me/sessions", func(r chi.Router) {
+					r.Get("/", s.listSessions())
+
+					r.With(s.sessionCtx, s.withOwner("session")).Delete("/{id}", s.revokeSession())
+				})
+
+				// personal access tokens for scripts
+				r.Route("/me/tokens", func(r chi.Router) {
+					r.Get("/", s.listPersonalAccessTokens())
+					r.Post("/", s.createPersonalAccessToken())
+
+					r.Route("/{id}", func(r chi.Router) {
+						r.Use(s.personalAccessTokenCtx)
+						r.Use(s.withOwner("tokenParam"))
+
+						r.Get("/", s.getPersonalAccessToken())
+						r.Patch("/", s.updatePersonalAccessToken())
+						r.Delete("/", s.revokePersonalAccessToken())
+					})
+				})
+			})
 
 		})
 
 		r.Route("/todos", func(r chi.Router) {
 			// Use the middleware we created
 			r.Use(s.withUser)
-			r.Post("/", s.createTodo())
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.withScope("todos"))
+				r.Get("/", s.listTodos())
+				r.Post("/", s.createTodo())
+				r.Post("/quick", s.quickAddTodo())
+
+				// archived todos and the rule that archives them automatically
+				r.Get("/archive", s.listArchivedTodos())
+				r.Get("/archive/rule", s.getArchiveRule())
+				r.Put("/archive/rule", s.setArchiveRule())
+			})
 
 			// extract the id from the context
 			r.Route("/{id}", func(r chi.Router) {
-				// and now use the todo context in the middleware
-				r.Use(s.todoCtx)
+				r.Group(func(r chi.Router) {
+					r.Use(s.withScope("todos"))
+
+					// and now use the todo context in the middleware
+					r.Use(s.todoCtx)
+
+					// verify that the user of the context is the owner of the todo id:
+					// we passs the subject type. In our case, is the "todo"
+					r.Use(s.withOwner("todo"))
+
+					r.Get("/", s.getTodo())
+					r.Patch("/", s.updateTodo())
+					r.Delete("/", s.deleteTodo())
+					r.Post("/archive", s.archiveTodo())
+					r.Post("/unarchive", s.unarchiveTodo())
+					r.Post("/snooze", s.snoozeTodo())
+					r.Delete("/snooze", s.unsnoozeTodo())
+
+					// only the creator can (un)assign, the assignee answers through /assignments
+					// reaching other users requires a verified email
+					r.With(s.requireVerifiedEmail).Post("/assignment", s.assignTodo())
+					r.Delete("/assignment", s.unassignTodo())
+
+					r.Get("/comments", s.listComments())
+					r.With(s.requireVerifiedEmail).Post("/comments", s.createComment())
 
-				// verify that the user of the context is the owner of the todo id:
-				// we passs the subject type. In our case, is the "todo"
-				r.Use(s.withOwner("todo"))
+					// "blocked by" dependencies of this todo
+					r.Get("/dependencies", s.listDependencies())
+					r.Post("/dependencies", s.addDependency())
+					r.Delete("/dependencies/{blockedById}", s.removeDependency())
+				})
 
-				r.Patch("/", s.updateTodo())
-				r.Delete("/", s.deleteTodo())
+				// time tracking of this todo, it takes the time scope like /time-entries
+				r.Group(func(r chi.Router) {
+					r.Use(s.withScope("time"))
+					r.Use(s.todoCtx)
+					r.Use(s.withOwner("todo"))
+
+					r.Post("/timer/start", s.startTimer())
+					r.Post("/timer/stop", s.stopTimer())
+					r.Get("/time-entries", s.listTimeEntries())
+					r.Post("/time-entries", s.createTimeEntry())
+				})
+			})
+		})
+
+		r.Route("/assignments", func(r chi.Router) {
+			r.Use(s.withUser)
+			r.Use(s.withScope("todos"))
+			r.Get("/", s.listPendingAssignments())
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Use(s.assignmentCtx)
+				r.Use(s.withOwner("assignment"))
+
+				r.Post("/accept", s.acceptAssignment())
+				r.Post("/decline", s.declineAssignment())
+			})
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(s.withUser)
+			r.Use(s.withScope("notifications"))
+			r.Get("/", s.listNotifications())
+			r.Get("/unread-count", s.unreadNotificationsCount())
+			r.Post("/read-all", s.markAllNotificationsRead())
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Use(s.notificationCtx)
+				r.Use(s.withOwner("notification"))
+
+				r.Post("/read", s.markNotificationRead())
+			})
+		})
+
+		r.Route("/projects", func(r chi.Router) {
+			r.Use(s.withUser)
+			r.Use(s.withScope("projects"))
+			r.Get("/", s.listProjects())
+			r.Post("/", s.createProject())
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Use(s.projectCtx)
+				r.Use(s.withOwner("project"))
+
+				r.Get("/", s.getProject())
+				r.Get("/workflow", s.getProjectWorkflow())
+				r.Put("/workflow", s.setProjectWorkflow())
+			})
+		})
+
+		// support staff, see admin.go
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(s.withUser)
+			r.Use(s.withoutPersonalAccessToken)
+			r.Use(s.requireRole(domain.RoleAdmin))
+
+			r.Get("/users", s.searchUsers())
+			r.Get("/audit-logs", s.listAuditLogs())
+
+			r.Route("/users/{id}", func(r chi.Router) {
+				r.Use(s.targetUserCtx)
+
+				r.Get("/", s.adminGetUser())
+				r.Get("/todos", s.adminListUserTodos())
+				r.Post("/disable", s.disableUser())
+				r.Post("/enable", s.enableUser())
+				r.Post("/password-reset", s.forcePasswordReset())
 			})
 		})
 
+		// reports over all the tracked time of the current user
+		r.Route("/time-entries", func(r chi.Router) {
+			r.Use(s.withUser)
+			r.Use(s.withScope("time"))
+			r.Get("/report", s.timeReport())
+			r.Get("/export", s.exportTimesheet())
+		})
+
 	})
 
 }
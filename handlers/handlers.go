@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -110,15 +111,20 @@ type PayloadValidation interface {
 
 // validatePyaload will decode the body to json and also validate each field
 // It takes the original http.HandlerFunc and handles the requests using validatePayload content
-func validatePayload(next http.HandlerFunc, payload PayloadValidation) http.HandlerFunc {
+// payloadType is a nil pointer of the payload, e.g (*domain.LoginPayload)(nil): every request decodes
+// into a new one, so the fields of a previous request can't leak, and the handler reads it with payloadFromCTX
+func validatePayload(next http.HandlerFunc, payloadType PayloadValidation) http.HandlerFunc {
+	typ := reflect.TypeOf(payloadType).Elem()
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		payload := reflect.New(typ).Interface().(PayloadValidation)
 
 		// To understand better http.Handler wrapper: https://medium.com/@matryer/the-http-handler-wrapper-technique-in-golang-updated-bc7fbcffa702
 
 		// the Decode method will look at the JSON tag directly, in that way we avoid marshalling
 		// Basically this can be read as: a request handler is received. So, we create a new enconder (which is the body sent)
 		// and decode it using the payload struct, which as JSON tags
-		err := json.NewDecoder(r.Body).Decode(&payload)
+		err := json.NewDecoder(r.Body).Decode(payload)
 
 		if err != nil {
 			badRequestResponse(w, err)
@@ -148,6 +154,11 @@ func validatePayload(next http.HandlerFunc, payload PayloadValidation) http.Hand
 	}
 }
 
+// payloadFromCTX returns the payload validatePayload decoded for the request
+func payloadFromCTX(r *http.Request) PayloadValidation {
+	return r.Context().Value("payload").(PayloadValidation)
+}
+
 //												\/ returns a function with a middleware and the handler
 // 												This is done so we can use the output easily (see "endpoints")
 func (s *Server) withOwner(subjectType string) func(next http.Handler) http.Handler {
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// Social login with the OpenID providers. The web app sends the user to the authorization url
+// and posts the code and state it gets back on the redirect url to the callback
+
+func (s *Server) listOIDCProviders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.domain.OIDCProviderNames(), http.StatusOK)
+	}
+}
+
+func (s *Server) beginOIDCLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization, err := s.domain.BeginOIDCLogin(chi.URLParam(r, "provider"))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, authorization, http.StatusOK)
+	}
+}
+
+// Same answer as loginUser, including the second factor
+
+func (s *Server) oidcCallback() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.OIDCCallbackPayload)
+
+		user, err := s.domain.FinishOIDCLogin(chi.URLParam(r, "provider"), payload)
+		if err != nil {
+			jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if user.IsMFAEnabled() {
+			challenge, err := s.domain.NewMFAChallenge(user)
+			if err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+
+			jsonResponse(w, challenge, http.StatusOK)
+			return
+		}
+
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &authResponse{
+			User:  user,
+			Token: token,
+		}, http.StatusOK)
+	}, (*domain.OIDCCallbackPayload)(nil))
+}
+
+func (s *Server) listIdentities() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identities, err := s.domain.ListIdentities(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, identities, http.StatusOK)
+	}
+}
+
+// Links a provider to the current user. The web app posts the code and state of the redirect
+// to linkIdentityCallback, with the token of the session that began the link
+
+func (s *Server) linkIdentity() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.LinkIdentityPayload)
+
+		sessionID := domain.SessionIDFromToken(s.tokenFromCTX(r))
+
+		authorization, err := s.domain.BeginOIDCLink(payload.Provider, s.currentUserFromCTX(r), sessionID)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, authorization, http.StatusOK)
+	}, (*domain.LinkIdentityPayload)(nil))
+}
+
+func (s *Server) linkIdentityCallback() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.OIDCCallbackPayload)
+
+		sessionID := domain.SessionIDFromToken(s.tokenFromCTX(r))
+
+		identity, err := s.domain.FinishOIDCLink(chi.URLParam(r, "provider"), payload, s.currentUserFromCTX(r), sessionID)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, identity, http.StatusCreated)
+	}, (*domain.OIDCCallbackPayload)(nil))
+}
+
+func (s *Server) unlinkIdentity() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.domain.UnlinkIdentity(s.identityFromCTX(r), s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+func (s *Server) identityCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		identity, err := s.domain.GetIdentityByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "identity", identity)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) identityFromCTX(r *http.Request) *domain.UserIdentity {
+	identity := r.Context().Value("identity").(*domain.UserIdentity)
+	return identity
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo/domain"
+	"todo/handlers"
+)
+
+type loginUserRepo struct {
+	domain.UserRepo
+	user *domain.User
+}
+
+func (l *loginUserRepo) GetByEmail(email string) (*domain.User, error) {
+	if l.user == nil || !strings.EqualFold(l.user.Email, email) {
+		return nil, domain.ErrNoResult
+	}
+	return l.user, nil
+}
+
+func TestLoginThrottleResponse(t *testing.T) {
+	hasher := &domain.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := hasher.Hash("tangerine-orbit-57")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "known account", email: "bob@example.com"},
+		{name: "unknown account", email: "nobody@example.com"},
+	}
+
+	var bodies []string
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &domain.Domain{
+				DB: domain.DB{
+					UserRepo: &loginUserRepo{user: &domain.User{ID: 1, Username: "bob", Email: "bob@example.com", Password: hash}},
+				},
+				LoginAttempts:  domain.NewMemoryLoginAttemptStore(),
+				PasswordHasher: hasher,
+			}
+			router := handlers.SetupRouter(d)
+
+			var w *httptest.ResponseRecorder
+			for i := 1; i <= 6; i++ {
+				body := `{"email": "` + tt.email + `", "password": "wrong password"}`
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/users/login", strings.NewReader(body)))
+
+				if i <= 5 && w.Code != http.StatusBadRequest {
+					t.Fatalf("attempt %d: got %d, want %d", i, w.Code, http.StatusBadRequest)
+				}
+			}
+
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("got %d, want %d", w.Code, http.StatusTooManyRequests)
+			}
+			if got := w.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want %q", got, "1")
+			}
+
+			bodies = append(bodies, w.Body.String())
+		})
+	}
+
+	if len(bodies) == 2 && bodies[0] != bodies[1] {
+		t.Errorf("the known account got %q, the unknown one %q", bodies[0], bodies[1])
+	}
+}
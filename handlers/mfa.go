@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"rsc.io/qr"
+)
+
+// Second step of the login for the users with two-factor enabled
+
+func (s *Server) loginMFA() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.MFALoginPayload)
+
+		user, err := s.domain.LoginMFA(payload)
+		if err != nil {
+			var throttled domain.ErrTooManyLoginAttempts
+			if errors.As(err, &throttled) {
+				w.Header().Set("Retry-After", strconv.Itoa(throttled.RetrySeconds()))
+				jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusTooManyRequests)
+				return
+			}
+
+			jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &authResponse{
+			User:  user,
+			Token: token,
+		}, http.StatusOK)
+	}, (*domain.MFALoginPayload)(nil))
+}
+
+func (s *Server) beginTOTPEnrollment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enrollment, err := s.domain.BeginTOTPEnrollment(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, enrollment, http.StatusCreated)
+	}
+}
+
+// QR code of the enrollment waiting for confirmation, to scan with the authenticator app
+func (s *Server) totpEnrollmentQR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uri, err := s.domain.TOTPEnrollmentURI(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		code, err := qr.Encode(uri, qr.M)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(code.PNG())
+	}
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (s *Server) confirmTOTPEnrollment() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.MFACodePayload)
+
+		codes, err := s.domain.ConfirmTOTPEnrollment(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &recoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+	}, (*domain.MFACodePayload)(nil))
+}
+
+func (s *Server) disableTOTP() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.MFAPayload)
+
+		if err := s.domain.DisableTOTP(s.currentUserFromCTX(r), payload); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}, (*domain.MFAPayload)(nil))
+}
+
+func (s *Server) regenerateRecoveryCodes() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.MFACodePayload)
+
+		codes, err := s.domain.RegenerateRecoveryCodes(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &recoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+	}, (*domain.MFACodePayload)(nil))
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) listNotifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		notifications, err := s.domain.ListNotifications(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, notifications, http.StatusOK)
+	}
+}
+
+func (s *Server) unreadNotificationsCount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count, err := s.domain.CountUnreadNotifications(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, map[string]int{"count": count}, http.StatusOK)
+	}
+}
+
+func (s *Server) markNotificationRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		notification, err := s.domain.MarkNotificationRead(s.notificationFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, notification, http.StatusOK)
+	}
+}
+
+func (s *Server) markAllNotificationsRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.domain.MarkAllNotificationsRead(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+func (s *Server) notificationCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		notification, err := s.domain.GetNotificationByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "notification", notification)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) notificationFromCTX(r *http.Request) *domain.Notification {
+	notification := r.Context().Value("notification").(*domain.Notification)
+	return notification
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// WebAuthn ceremonies. Each begin returns the options for the browser and a session token,
+// the finish endpoints take the session token back with the result of the browser
+
+func (s *Server) beginPasskeyRegistration() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ceremony, err := s.domain.BeginPasskeyRegistration(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, ceremony, http.StatusOK)
+	}
+}
+
+func (s *Server) finishPasskeyRegistration() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.FinishPasskeyRegistrationPayload)
+
+		passkey, err := s.domain.FinishPasskeyRegistration(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, passkey, http.StatusCreated)
+	}, (*domain.FinishPasskeyRegistrationPayload)(nil))
+}
+
+func (s *Server) beginPasskeyLogin() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.BeginPasskeyLoginPayload)
+
+		ceremony, err := s.domain.BeginPasskeyLogin(payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, ceremony, http.StatusOK)
+	}, (*domain.BeginPasskeyLoginPayload)(nil))
+}
+
+// Same answer as loginUser
+
+func (s *Server) finishPasskeyLogin() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.FinishPasskeyLoginPayload)
+
+		user, err := s.domain.FinishPasskeyLogin(payload)
+		if err != nil {
+			jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &authResponse{
+			User:  user,
+			Token: token,
+		}, http.StatusOK)
+	}, (*domain.FinishPasskeyLoginPayload)(nil))
+}
+
+func (s *Server) listPasskeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		passkeys, err := s.domain.ListPasskeys(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, passkeys, http.StatusOK)
+	}
+}
+
+func (s *Server) deletePasskey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.domain.DeletePasskey(s.passkeyFromCTX(r), s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+func (s *Server) passkeyCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		passkey, err := s.domain.GetPasskeyByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "passkey", passkey)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) passkeyFromCTX(r *http.Request) *domain.Passkey {
+	passkey := r.Context().Value("passkey").(*domain.Passkey)
+	return passkey
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) listPersonalAccessTokens() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := s.domain.ListPersonalAccessTokens(s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, tokens, http.StatusOK)
+	}
+}
+
+// The token is only in this response, we keep its hash
+
+func (s *Server) createPersonalAccessToken() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.CreatePersonalAccessTokenPayload)
+
+		token, err := s.domain.CreatePersonalAccessToken(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, token, http.StatusCreated)
+	}, (*domain.CreatePersonalAccessTokenPayload)(nil))
+}
+
+func (s *Server) getPersonalAccessToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.personalAccessTokenParamFromCTX(r), http.StatusOK)
+	}
+}
+
+func (s *Server) updatePersonalAccessToken() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.UpdatePersonalAccessTokenPayload)
+
+		token, err := s.domain.UpdatePersonalAccessToken(s.personalAccessTokenParamFromCTX(r), payload, s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, token, http.StatusOK)
+	}, (*domain.UpdatePersonalAccessTokenPayload)(nil))
+}
+
+func (s *Server) revokePersonalAccessToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.domain.RevokePersonalAccessToken(s.personalAccessTokenParamFromCTX(r), s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+// the token of the url, not to confuse with the one the request is authenticated with
+func (s *Server) personalAccessTokenCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		token, err := s.domain.GetPersonalAccessTokenByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "tokenParam", token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) personalAccessTokenParamFromCTX(r *http.Request) *domain.PersonalAccessToken {
+	token := r.Context().Value("tokenParam").(*domain.PersonalAccessToken)
+	return token
+}
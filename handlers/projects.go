@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) createProject() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.CreateProjectPayload)
+
+		currentUser := s.currentUserFromCTX(r)
+		project, err := s.domain.CreateProject(payload, currentUser)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, project, http.StatusCreated)
+
+	}, (*domain.CreateProjectPayload)(nil))
+}
+
+func (s *Server) listProjects() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projects, err := s.domain.ListProjects(s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, projects, http.StatusOK)
+	}
+}
+
+func (s *Server) getProject() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.projectFromCTX(r), http.StatusOK)
+	}
+}
+
+// same as todoCtx, but for the "project" key
+func (s *Server) projectCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		project, err := s.domain.GetProjectByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "project", project)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) projectFromCTX(r *http.Request) *domain.Project {
+	project := r.Context().Value("project").(*domain.Project)
+	return project
+}
+
+func (s *Server) getProjectWorkflow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.projectFromCTX(r).GetWorkflow(), http.StatusOK)
+	}
+}
+
+func (s *Server) setProjectWorkflow() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.WorkflowPayload)
+
+		project, err := s.domain.SetProjectWorkflow(s.projectFromCTX(r), payload)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, project, http.StatusOK)
+
+	}, (*domain.WorkflowPayload)(nil))
+}
@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo/domain"
+	"todo/handlers"
+)
+
+// In memory repos for a user with a single todo, authenticated with a personal access token
+
+type patUserRepo struct {
+	domain.UserRepo
+	user *domain.User
+}
+
+func (p *patUserRepo) GetByID(id int64) (*domain.User, error) {
+	return p.user, nil
+}
+
+type patRepo struct {
+	domain.PersonalAccessTokenRepo
+	pat *domain.PersonalAccessToken
+}
+
+func (p *patRepo) GetByHash(hash string) (*domain.PersonalAccessToken, error) {
+	return p.pat, nil
+}
+
+func (p *patRepo) TouchLastUsed(pat *domain.PersonalAccessToken) error {
+	return nil
+}
+
+type patTodoRepo struct {
+	domain.TodoRepo
+	todo *domain.Todo
+}
+
+func (p *patTodoRepo) GetByID(id int64) (*domain.Todo, error) {
+	return p.todo, nil
+}
+
+type patDependencyRepo struct {
+	domain.DependencyRepo
+}
+
+func (p *patDependencyRepo) ListByTodo(todoID int64) ([]*domain.DependencyEdge, error) {
+	return nil, nil
+}
+
+type patTimeEntryRepo struct {
+	domain.TimeEntryRepo
+}
+
+func (p *patTimeEntryRepo) GetRunning(userID int64) (*domain.TimeEntry, error) {
+	return nil, domain.ErrNoResult
+}
+
+func (p *patTimeEntryRepo) Create(entry *domain.TimeEntry) (*domain.TimeEntry, error) {
+	entry.ID = 1
+	return entry, nil
+}
+
+func (p *patTimeEntryRepo) ListByTodo(todoID int64) ([]*domain.TimeEntry, error) {
+	return []*domain.TimeEntry{}, nil
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	tests := []struct {
+		scope  string
+		method string
+		path   string
+		want   int
+	}{
+		{domain.ScopeTodosRead, http.MethodGet, "/api/v1/todos/1", http.StatusOK},
+		{domain.ScopeTimeWrite, http.MethodGet, "/api/v1/todos/1", http.StatusForbidden},
+		{domain.ScopeTimeWrite, http.MethodPost, "/api/v1/todos/1/timer/start", http.StatusCreated},
+		{domain.ScopeTodosWrite, http.MethodPost, "/api/v1/todos/1/timer/start", http.StatusForbidden},
+		{domain.ScopeTimeRead, http.MethodPost, "/api/v1/todos/1/timer/start", http.StatusForbidden},
+		{domain.ScopeTimeRead, http.MethodGet, "/api/v1/todos/1/time-entries", http.StatusOK},
+		{domain.ScopeTodosWrite, http.MethodGet, "/api/v1/todos/1/time-entries", http.StatusForbidden},
+		{domain.ScopeTodosWrite, http.MethodPost, "/api/v1/todos/1/time-entries", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			user := &domain.User{ID: 1, Username: "bob"}
+
+			d := &domain.Domain{
+				DB: domain.DB{
+					UserRepo: &patUserRepo{user: user},
+					PersonalAccessTokenRepo: &patRepo{pat: &domain.PersonalAccessToken{
+						ID:     1,
+						UserID: user.ID,
+						Scopes: []string{tt.scope},
+					}},
+					TodoRepo:       &patTodoRepo{todo: &domain.Todo{ID: 1, Title: "Invoice", UserID: user.ID}},
+					DependencyRepo: &patDependencyRepo{},
+					TimeEntryRepo:  &patTimeEntryRepo{},
+				},
+			}
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer "+domain.PersonalAccessTokenPrefix+"secret")
+			w := httptest.NewRecorder()
+
+			handlers.SetupRouter(d).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"todo/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// The devices the user is logged in from, the one of the request is marked as current
+func (s *Server) listSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current := domain.SessionIDFromToken(s.tokenFromCTX(r))
+
+		sessions, err := s.domain.ListSessions(s.currentUserFromCTX(r), current)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, sessions, http.StatusOK)
+	}
+}
+
+func (s *Server) revokeSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.domain.RevokeSession(s.sessionFromCTX(r), s.currentUserFromCTX(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+func (s *Server) sessionCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 0, 0)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		session, err := s.domain.GetSessionByID(id)
+
+		if err != nil {
+			response := map[string]string{
+				"error": domain.ErrNoResult.Error(),
+			}
+
+			jsonResponse(w, response, http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "session", session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) sessionFromCTX(r *http.Request) *domain.Session {
+	session := r.Context().Value("session").(*domain.Session)
+	return session
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"todo/domain"
+)
+
+func (s *Server) snoozeTodo() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.SnoozePayload)
+
+		todo, err := s.domain.SnoozeTodo(s.todoFromCTX(r), payload, s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todo, http.StatusOK)
+
+	}, (*domain.SnoozePayload)(nil))
+}
+
+func (s *Server) unsnoozeTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		todo, err := s.domain.UnsnoozeTodo(s.todoFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todo, http.StatusOK)
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"todo/domain"
+)
+
+// Time tracking handlers. The todo ones are mounted under /todos/{id}, so todoCtx and withOwner already ran
+
+func (s *Server) startTimer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, err := s.domain.StartTimer(s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, entry, http.StatusCreated)
+	}
+}
+
+func (s *Server) stopTimer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, err := s.domain.StopTimer(s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, entry, http.StatusOK)
+	}
+}
+
+func (s *Server) createTimeEntry() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.CreateTimeEntryPayload)
+
+		entry, err := s.domain.CreateTimeEntry(payload, s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, entry, http.StatusCreated)
+
+	}, (*domain.CreateTimeEntryPayload)(nil))
+}
+
+func (s *Server) listTimeEntries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := s.domain.ListTimeEntries(s.todoFromCTX(r), s.currentUserFromCTX(r))
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, entries, http.StatusOK)
+	}
+}
+
+// GET /time-entries/report?groupBy=todo|project|day&from=2020-10-01&to=2020-11-01, the days are the ones of the user timezone
+func (s *Server) timeReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := dateRangeFromQuery(r, s.currentUserFromCTX(r).Location())
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		groupBy := domain.TimeReportGroup(r.URL.Query().Get("groupBy"))
+		if groupBy == "" {
+			groupBy = domain.TimeReportByTodo
+		}
+
+		rows, err := s.domain.TimeReport(s.currentUserFromCTX(r), groupBy, from, to)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, rows, http.StatusOK)
+	}
+}
+
+// GET /time-entries/export?from=2020-10-01&to=2020-11-01 returns the timesheet as a CSV file
+func (s *Server) exportTimesheet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := s.currentUserFromCTX(r)
+		loc := user.Location()
+
+		from, to, err := dateRangeFromQuery(r, loc)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		entries, err := s.domain.Timesheet(user, from, to)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="timesheet.csv"`)
+		w.WriteHeader(http.StatusOK)
+
+		now := time.Now()
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"date", "todo_id", "todo", "project_id", "started_at", "stopped_at", "hours", "note"})
+
+		for _, entry := range entries {
+			title, projectID, stoppedAt := "", "", ""
+
+			if entry.Todo != nil {
+				title = entry.Todo.Title
+				if entry.Todo.ProjectID != nil {
+					projectID = strconv.FormatInt(*entry.Todo.ProjectID, 10)
+				}
+			}
+
+			if entry.StoppedAt != nil {
+				stoppedAt = entry.StoppedAt.UTC().Format(time.RFC3339)
+			}
+
+			cw.Write([]string{
+				entry.StartedAt.In(loc).Format("2006-01-02"),
+				strconv.FormatInt(entry.TodoID, 10),
+				title,
+				projectID,
+				entry.StartedAt.UTC().Format(time.RFC3339),
+				stoppedAt,
+				fmt.Sprintf("%.2f", entry.Duration(now).Hours()),
+				entry.Note,
+			})
+		}
+
+		cw.Flush()
+	}
+}
+
+// from and to are optional dates (YYYY-MM-DD) of the user timezone. to is inclusive, so we move it to the start of the next day
+func dateRangeFromQuery(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return from, to, err
+		}
+	}
+
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return from, to, err
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return from, to, nil
+}
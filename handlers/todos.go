@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"todo/domain"
+	"todo/quickadd"
 
 	"github.com/go-chi/chi"
 )
@@ -13,13 +16,11 @@ import (
 // Where we do everything related with our TODO handler
 
 func (s *Server) createTodo() http.HandlerFunc {
-	var payload domain.CreateTodoPayload
-
 	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.CreateTodoPayload)
 
 		// Getting the user from the context that we added in the jwt token
 		currentUser := s.currentUserFromCTX(r)
-		fmt.Println(currentUser)
 		todo, err := s.domain.CreateTodo(payload, currentUser)
 
 		if err != nil {
@@ -30,7 +31,106 @@ func (s *Server) createTodo() http.HandlerFunc {
 
 		jsonResponse(w, todo, http.StatusCreated)
 
-	}, &payload)
+	}, (*domain.CreateTodoPayload)(nil))
+}
+
+type quickAddResponse struct {
+	Todo   *domain.Todo     `json:"todo"`
+	Parsed *quickadd.Result `json:"parsed"`
+}
+
+func (s *Server) quickAddTodo() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.QuickAddPayload)
+
+		todo, parsed, err := s.domain.QuickAddTodo(payload, s.currentUserFromCTX(r))
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &quickAddResponse{
+			Todo:   todo,
+			Parsed: parsed,
+		}, http.StatusCreated)
+
+	}, (*domain.QuickAddPayload)(nil))
+}
+
+func (s *Server) listTodos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := todoFilterFromQuery(r)
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		todos, err := s.domain.ListTodos(s.currentUserFromCTX(r), filter)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, todos, http.StatusOK)
+	}
+}
+
+// todoFilterFromQuery reads the optional ?view=created|assigned&projectId=&status=&completed=&include=archived,snoozed
+// parameters of the listing
+func todoFilterFromQuery(r *http.Request) (domain.TodoFilter, error) {
+	var filter domain.TodoFilter
+	query := r.URL.Query()
+
+	if v := query.Get("projectId"); v != "" {
+		projectID, err := strconv.ParseInt(v, 0, 0)
+		if err != nil {
+			return filter, err
+		}
+		filter.ProjectID = &projectID
+	}
+
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.Completed = &completed
+	}
+
+	filter.Status = query.Get("status")
+	filter.View = query.Get("view")
+
+	for _, include := range strings.Split(query.Get("include"), ",") {
+		switch include {
+		case "archived":
+			filter.IncludeArchived = true
+		case "snoozed":
+			filter.IncludeSnoozed = true
+		}
+	}
+
+	return filter, nil
+}
+
+func (s *Server) getTodo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.todoFromCTX(r), http.StatusOK)
+	}
 }
 
 func (s *Server) todoCtx(next http.Handler) http.Handler {
@@ -63,13 +163,12 @@ func (s *Server) todoCtx(next http.Handler) http.Handler {
 }
 
 func (s *Server) updateTodo() http.HandlerFunc {
-	var payload domain.UpdateTodoPayload
-
 	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.UpdateTodoPayload)
 
 		// Getting the user from the context that we added in the jwt token
 
-		todo, err := s.domain.UpdateTodo(s.todoFromCTX(r), payload)
+		todo, err := s.domain.UpdateTodo(s.todoFromCTX(r), payload, s.currentUserFromCTX(r))
 
 		if err != nil {
 			badRequestResponse(w, err)
@@ -78,13 +177,18 @@ func (s *Server) updateTodo() http.HandlerFunc {
 
 		jsonResponse(w, todo, http.StatusOK)
 
-	}, &payload)
+	}, (*domain.UpdateTodoPayload)(nil))
 }
 
 func (s *Server) deleteTodo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		todo := s.todoFromCTX(r)
-		err := s.domain.DeleteTodo(todo)
+		err := s.domain.DeleteTodo(todo, s.currentUserFromCTX(r))
+
+		if errors.Is(err, domain.ErrForbidden) {
+			forbiddenResponse(w)
+			return
+		}
 
 		if err != nil {
 			badRequestResponse(w, err)
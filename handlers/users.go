@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
 	"todo/domain"
 
 	"github.com/dgrijalva/jwt-go"
@@ -10,15 +14,13 @@ import (
 
 type authResponse struct {
 	User  *domain.User     `json:"user"`
-	Token *domain.JWTToken `json:token`
+	Token *domain.JWTToken `json:"Token"`
 }
 
 // users handlers. Think of it as a controller
 
 func (s *Server) registerUser() http.HandlerFunc {
 	// Here we decode the JSON. Good news is that we dont need to marshall Go to json
-	var payload domain.RegisterPayload
-
 	// we return a http handler that has a context of the payload validated
 
 	// We test in postman the received request decoded
@@ -28,14 +30,23 @@ func (s *Server) registerUser() http.HandlerFunc {
 	// We will create our own validator library, returning an error for each key (i.e, if Email was bad written - EmailError)
 
 	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.RegisterPayload)
+
 		// once validated, we start to register our model
 		user, err := s.domain.Register(payload)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
 		if err != nil {
 			badRequestResponse(w, err)
 			return
 		}
 		// generate jwt token:
-		token, err := user.GenToken()
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
 		if err != nil {
 			badRequestResponse(w, err)
 			return
@@ -47,7 +58,7 @@ func (s *Server) registerUser() http.HandlerFunc {
 			Token: token,
 		}, http.StatusCreated)
 
-	}, &payload)
+	}, (*domain.RegisterPayload)(nil))
 
 }
 
@@ -55,8 +66,6 @@ func (s *Server) registerUser() http.HandlerFunc {
 
 func (s *Server) loginUser() http.HandlerFunc {
 	// Here we decode the JSON. Good news is that we dont need to marshall Go to json
-	var payload domain.LoginPayload
-
 	// we return a http handler that has a context of the payload validated
 
 	// We test in postman the received request decoded
@@ -66,14 +75,36 @@ func (s *Server) loginUser() http.HandlerFunc {
 	// We will create our own validator library, returning an error for each key (i.e, if Email was bad written - EmailError)
 
 	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.LoginPayload)
+
 		// once validated, we start to register our model
-		user, err := s.domain.Login(payload)
+		user, err := s.domain.Login(payload, clientIP(r))
 		if err != nil {
+			var throttled domain.ErrTooManyLoginAttempts
+			if errors.As(err, &throttled) {
+				w.Header().Set("Retry-After", strconv.Itoa(throttled.RetrySeconds()))
+				jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusTooManyRequests)
+				return
+			}
+
 			badRequestResponse(w, err)
 			return
 		}
+
+		// the password is not enough, the client continues at /users/login/mfa
+		if user.IsMFAEnabled() {
+			challenge, err := s.domain.NewMFAChallenge(user)
+			if err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+
+			jsonResponse(w, challenge, http.StatusOK)
+			return
+		}
+
 		// generate jwt token:
-		token, err := user.GenToken()
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
 		if err != nil {
 			badRequestResponse(w, err)
 			return
@@ -85,8 +116,225 @@ func (s *Server) loginUser() http.HandlerFunc {
 			Token: token,
 		}, http.StatusOK)
 
-	}, &payload)
+	}, (*domain.LoginPayload)(nil))
+
+}
+
+// Refresh the access token. The refresh token is rotated, the client must keep the new one
+
+func (s *Server) refreshToken() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.RefreshTokenPayload)
+
+		user, token, err := s.domain.RefreshTokens(payload, sessionClient(r))
+		if err != nil {
+			jsonResponse(w, map[string]string{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		jsonResponse(w, &authResponse{
+			User:  user,
+			Token: token,
+		}, http.StatusOK)
+
+	}, (*domain.RefreshTokenPayload)(nil))
+}
+
+// Logout revokes the current access token. Sending the refresh token in the body revokes it as well
+
+func (s *Server) logoutUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the body is optional
+		var payload domain.LogoutPayload
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+			defer r.Body.Close()
+		}
+
+		if err := s.domain.Logout(s.tokenFromCTX(r), payload); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+// The personal access tokens keep working unless the body asks for {"personalAccessTokens": true}
+
+func (s *Server) logoutEverywhere() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the body is optional
+		var payload domain.LogoutAllPayload
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				badRequestResponse(w, err)
+				return
+			}
+			defer r.Body.Close()
+		}
+
+		if err := s.domain.LogoutAll(s.currentUserFromCTX(r), payload); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}
+}
+
+func (s *Server) verifyEmail() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.VerifyEmailPayload)
+
+		user, err := s.domain.VerifyEmail(payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}, (*domain.VerifyEmailPayload)(nil))
+}
+
+func (s *Server) resendVerificationEmail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.domain.SendVerificationEmail(s.currentUserFromCTX(r)); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusAccepted)
+	}
+}
+
+// Always 202, whether the email belongs to an account or not
+
+func (s *Server) forgotPassword() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ForgotPasswordPayload)
+
+		s.domain.RequestPasswordReset(payload)
+
+		jsonResponse(w, nil, http.StatusAccepted)
+	}, (*domain.ForgotPasswordPayload)(nil))
+}
+
+func (s *Server) resetPassword() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ResetPasswordPayload)
+
+		err := s.domain.ResetPassword(payload)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusNoContent)
+	}, (*domain.ResetPasswordPayload)(nil))
+}
+
+func (s *Server) getCurrentUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonResponse(w, s.currentUserFromCTX(r), http.StatusOK)
+	}
+}
+
+func (s *Server) updateCurrentUser() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.UpdateProfilePayload)
+
+		user, err := s.domain.UpdateProfile(s.currentUserFromCTX(r), payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}, (*domain.UpdateProfilePayload)(nil))
+}
+
+// Every other session is logged out, the response has new tokens for this one
+
+func (s *Server) changePassword() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ChangePasswordPayload)
+
+		user := s.currentUserFromCTX(r)
+
+		err := s.domain.ChangePassword(user, payload)
+
+		var validationErr domain.ErrValidation
+		if errors.As(err, &validationErr) {
+			jsonResponse(w, validationErr.Errors, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		token, err := s.domain.IssueTokens(user, sessionClient(r))
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, &authResponse{
+			User:  user,
+			Token: token,
+		}, http.StatusOK)
+	}, (*domain.ChangePasswordPayload)(nil))
+}
+
+func (s *Server) changeEmail() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ChangeEmailPayload)
+
+		if err := s.domain.RequestEmailChange(s.currentUserFromCTX(r), payload); err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, nil, http.StatusAccepted)
+	}, (*domain.ChangeEmailPayload)(nil))
+}
+
+func (s *Server) confirmEmailChange() http.HandlerFunc {
+	return validatePayload(func(w http.ResponseWriter, r *http.Request) {
+		payload := *payloadFromCTX(r).(*domain.ConfirmEmailChangePayload)
+
+		user, err := s.domain.ConfirmEmailChange(payload)
+		if err != nil {
+			badRequestResponse(w, err)
+			return
+		}
+
+		jsonResponse(w, user, http.StatusOK)
+	}, (*domain.ConfirmEmailChangePayload)(nil))
+}
 
+func (s *Server) jwks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		jsonResponse(w, s.domain.Keys.JWKS(), http.StatusOK)
+	}
+}
+
+func (s *Server) tokenFromCTX(r *http.Request) *jwt.Token {
+	token := r.Context().Value("token").(*jwt.Token)
+	return token
 }
 
 func (s *Server) currentUserFromCTX(r *http.Request) *domain.User {
@@ -98,36 +346,120 @@ func (s *Server) currentUserFromCTX(r *http.Request) *domain.User {
 func (s *Server) withUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		token, err := domain.ParseToken(r)
+		// personal access tokens of scripts, limited to their scopes (see withScope)
+		if raw, err := domain.BearerToken(r); err == nil && domain.IsPersonalAccessToken(raw) {
+			user, pat, err := s.domain.AuthenticatePersonalAccessToken(raw)
+			if err != nil {
+				unauthorizedResponse(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "currentUser", user)
+			ctx = context.WithValue(ctx, "personalAccessToken", pat)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		token, err := s.domain.ParseToken(r)
 
 		if err != nil {
 			unauthorizedResponse(w)
 			return
 		}
 
-		// we check the claims of the JWT - VWhat we pass to the users
+		// we check the claims of the JWT (signature, expiration, logout...) and get the user
+		user, err := s.domain.AuthenticateToken(token)
+
+		if err != nil {
+			unauthorizedResponse(w)
+			return
+		}
 
-		// 									\/ Map the JWT
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		// If everything correct, return a context to the handler wrapper (middleware)
+		ctx := context.WithValue(r.Context(), "currentUser", user)
+		ctx = context.WithValue(ctx, "token", token)
 
-			userID := int64(claims["id"].(float64)) // the claim is in float, but our userID is int64
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 
-			user, err := s.domain.GetUserByID(userID)
+}
 
-			if err != nil {
-				unauthorizedResponse(w)
+// Middleware for the routes unverified users can't use, it goes after withUser
+func (s *Server) requireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.currentUserFromCTX(r).IsEmailVerified() {
+			jsonResponse(w, map[string]string{"error": domain.ErrEmailNotVerified.Error()}, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) personalAccessTokenFromCTX(r *http.Request) *domain.PersonalAccessToken {
+	pat, _ := r.Context().Value("personalAccessToken").(*domain.PersonalAccessToken)
+	return pat
+}
+
+// Middleware that checks the scopes of personal access tokens, reading needs resource:read and
+// everything else resource:write. Users logged in with a JWT can use every route
+func (s *Server) withScope(resource string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pat := s.personalAccessTokenFromCTX(r)
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+
+			if pat != nil && !pat.HasScope(resource, write) {
+				forbiddenResponse(w)
 				return
 			}
 
-			// If everything correct, return a context to the handler wrapper (middleware)
-			ctx := context.WithValue(r.Context(), "currentUser", user)
-
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		} else {
-			unauthorizedResponse(w)
+// Middleware for the account routes (logout, tokens, two-factor...), personal access tokens can't use them
+func (s *Server) withoutPersonalAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.personalAccessTokenFromCTX(r) != nil {
+			forbiddenResponse(w)
 			return
 		}
+
+		next.ServeHTTP(w, r)
 	})
+}
+
+// Middleware for the routes of some roles only, it goes after withUser
+func (s *Server) requireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.currentUserFromCTX(r).HasRole(roles...) {
+				forbiddenResponse(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sessionClient describes the client of a login, for its session
+func sessionClient(r *http.Request) domain.SessionClient {
+	return domain.SessionClient{
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+}
 
+// clientIP is the address of the client, middleware.RealIP already took it from
+// X-Forwarded-For or X-Real-IP when the API is behind a proxy
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
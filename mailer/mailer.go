@@ -0,0 +1,90 @@
+// Package mailer has the implementations of domain.Mailer
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"todo/domain"
+)
+
+// SMTP sends the emails through an SMTP server (or a local sink like MailHog while developing)
+type SMTP struct {
+	Addr     string // host:port
+	From     string
+	Username string // no authentication when empty
+	Password string
+}
+
+func (s *SMTP) Send(message domain.Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := strings.Split(s.Addr, ":")[0]
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	return smtp.SendMail(s.Addr, auth, s.From, []string{message.To}, s.format(message))
+}
+
+func (s *SMTP) format(message domain.Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// Memory keeps the emails instead of sending them, to check them in tests
+type Memory struct {
+	mu       sync.Mutex
+	messages []domain.Message
+}
+
+func (m *Memory) Send(message domain.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages returns the emails sent so far
+func (m *Memory) Messages() []domain.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]domain.Message(nil), m.messages...)
+}
+
+// Last returns the last email sent to the address
+func (m *Memory) Last(to string) (domain.Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+
+	return domain.Message{}, false
+}
+
+// Log writes the emails to the log, useful while developing without an SMTP server
+type Log struct{}
+
+func (Log) Send(message domain.Message) error {
+	log.Printf("email to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
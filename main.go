@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 
+	"todo/breached"
 	"todo/domain"
 	"todo/handlers"
+	"todo/mailer"
+	"todo/oidc"
 	"todo/postgres"
+	"todo/storage"
+	"todo/webauthn"
 )
 
 func main() {
@@ -22,12 +30,131 @@ func main() {
 
 	defer DB.Close()
 
-	domainDB := domain.DB{
-		UserRepo: postgres.NewUserRepo(DB),
-		TodoRepo: postgres.NewTodoRepo(DB),
+	// the keys of the usernames and emails can't be computed by the migration
+	if err := postgres.BackfillUserKeys(DB); err != nil {
+		log.Fatalf("cannot fill the keys of the users %v", err)
 	}
 
-	d := &domain.Domain{DB: domainDB}
+	domainDB := postgres.Repos(DB)
+
+	// revoked tokens are kept in postgres so every instance sees them, unless we run a single instance
+	var revocations domain.RevocationStore
+	revocationStore := postgres.NewRevocationStore(DB)
+	if os.Getenv("TOKEN_REVOCATION_STORE") == "memory" {
+		revocations = domain.NewMemoryRevocationStore()
+	} else {
+		revocations = revocationStore
+	}
+
+	// same for the failed logins, so the brute-force protection holds across instances
+	var loginAttempts domain.LoginAttemptStore
+	loginAttemptStore := postgres.NewLoginAttemptStore(DB)
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "memory" {
+		loginAttempts = domain.NewMemoryLoginAttemptStore()
+	} else {
+		loginAttempts = loginAttemptStore
+	}
+
+	passwordPolicy, err := domain.PasswordPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("cannot load the password policy %v", err)
+	}
+	if dir := os.Getenv("BREACHED_PASSWORDS_DIR"); dir != "" {
+		passwordPolicy.Breached = breached.NewPrefixDir(dir)
+	}
+
+	passwordHasher, err := domain.PasswordHasherFromEnv()
+	if err != nil {
+		log.Fatalf("cannot load the password hasher %v", err)
+	}
+
+	keys, err := domain.KeyRingFromEnv()
+	if err != nil {
+		log.Fatalf("cannot load the jwt keys %v", err)
+	}
+
+	// emails go through SMTP when configured (MailHog works while developing), otherwise to the log
+	var m domain.Mailer = mailer.Log{}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		m = &mailer.SMTP{
+			Addr:     addr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:3000"
+	}
+
+	secrets, err := domain.SecretBoxFromEnv()
+	if err != nil {
+		log.Fatalf("cannot load the encryption key %v", err)
+	}
+	if secrets == nil {
+		log.Println("MFA_ENCRYPTION_KEY is not set, two-factor authentication is disabled")
+	}
+
+	// passkeys are bound to the domain of the web app
+	relyingParty := &webauthn.RelyingParty{
+		ID:      os.Getenv("WEBAUTHN_RP_ID"),
+		Name:    "Todo",
+		Origins: []string{appURL},
+	}
+	if relyingParty.ID == "" {
+		relyingParty.ID = "localhost"
+	}
+	if origins := os.Getenv("WEBAUTHN_ORIGINS"); origins != "" {
+		relyingParty.Origins = strings.Split(origins, ",")
+	}
+
+	// e.g [{"name": "google", "issuer": "https://accounts.google.com", "clientId": "...", "clientSecret": "...", "redirectUrl": "..."}]
+	oidcProviders := map[string]*oidc.Provider{}
+	if config := os.Getenv("OIDC_PROVIDERS"); config != "" {
+		oidcProviders, err = oidc.ParseProviders(config)
+		if err != nil {
+			log.Fatalf("cannot load the login providers %v", err)
+		}
+	}
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "exports"
+	}
+	files, err := storage.NewDisk(exportDir)
+	if err != nil {
+		log.Fatalf("cannot create the export directory %v", err)
+	}
+
+	d := &domain.Domain{
+		DB:             domainDB,
+		Notifier:       &domain.InboxNotifier{Repo: domainDB.NotificationRepo},
+		Revocations:    revocations,
+		LoginAttempts:  loginAttempts,
+		PasswordPolicy: passwordPolicy,
+		PasswordHasher: passwordHasher,
+		Keys:           keys,
+		Mailer:         m,
+		AppURL:         appURL,
+		Secrets:        secrets,
+		WebAuthn:       relyingParty,
+		OIDCProviders:  oidcProviders,
+		Files:          files,
+
+		KeepEmailLocalPartCase: os.Getenv("EMAIL_KEEP_LOCAL_PART_CASE") == "true",
+	}
+
+	// background jobs
+	ctx := context.Background()
+	go domain.RunJob(ctx, "archive completed todos", time.Hour, d.ArchiveCompletedTodos)
+	go domain.RunJob(ctx, "wake snoozed todos", time.Minute, d.WakeSnoozedTodos)
+	go domain.RunJob(ctx, "delete expired revoked tokens", time.Hour, revocationStore.DeleteExpired)
+	go domain.RunJob(ctx, "delete expired login states", time.Hour, d.DeleteExpiredOIDCStates)
+	go domain.RunJob(ctx, "delete expired login attempts", time.Hour, loginAttemptStore.DeleteExpired)
+	go domain.RunJob(ctx, "delete expired sessions", time.Hour, d.DeleteExpiredSessions)
+	go domain.RunJob(ctx, "account exports and deletions", time.Minute, d.RunAccountJobs)
 
 	r := handlers.SetupRouter(d)
 
@@ -36,7 +163,7 @@ func main() {
 		port = "8082"
 	}
 	// ListenAndServe starts an HTTP server with a given address and handler
-	err := http.ListenAndServe(fmt.Sprintf(":%s", port), r)
+	err = http.ListenAndServe(fmt.Sprintf(":%s", port), r)
 	if err != nil {
 		log.Fatalf("cannot start server %v", err)
 	}
@@ -0,0 +1,347 @@
+// Package oidc is a small OpenID Connect client: discovery, the authorization code flow
+// with PKCE and the verification of the ID tokens with the keys of the provider
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrIssuerMismatch  = errors.New("oidc: issuer does not match")
+	ErrInvalidIDToken  = errors.New("oidc: invalid id token")
+	ErrNonceMismatch   = errors.New("oidc: nonce does not match")
+	ErrUnknownKey      = errors.New("oidc: unknown signing key")
+	ErrNoIDToken       = errors.New("oidc: the token response has no id token")
+	ErrTokenExchange   = errors.New("oidc: cannot exchange the code")
+	ErrUnsupportedKey  = errors.New("oidc: unsupported key")
+	ErrDiscoveryFailed = errors.New("oidc: discovery failed")
+)
+
+const (
+	// how long the discovery document and the keys are cached
+	cacheTTL = time.Hour
+	// unknown kids refresh the keys, but not more often than this
+	minRefreshInterval = time.Minute
+
+	leeway = time.Minute
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Provider is an OpenID provider we log in with, configured by its issuer URL
+type Provider struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes"` // openid email profile by default
+
+	mu          sync.Mutex
+	discovery   *discovery
+	discoveryAt time.Time
+	keys        map[string]interface{}
+	keysAt      time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// ParseProviders reads the providers from their JSON configuration, a list of Provider
+func ParseProviders(config string) (map[string]*Provider, error) {
+	var list []*Provider
+	if err := json.Unmarshal([]byte(config), &list); err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]*Provider, len(list))
+	for _, provider := range list {
+		if provider.Name == "" || provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("oidc: provider %q needs a name, issuer and clientId", provider.Name)
+		}
+
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+
+		providers[provider.Name] = provider
+	}
+
+	return providers, nil
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveryAt) < cacheTTL {
+		return p.discovery, nil
+	}
+
+	var doc discovery
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, wellKnown, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Issuer != p.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, ErrDiscoveryFailed
+	}
+
+	p.discovery = &doc
+	p.discoveryAt = time.Now()
+
+	return p.discovery, nil
+}
+
+// AuthCodeURL is where the user authorizes the login. The verifier of the challenge stays with us
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.ClientID)
+	values.Set("redirect_uri", p.RedirectURL)
+	values.Set("scope", strings.Join(p.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return doc.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+type tokenResponse struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+}
+
+// Exchange trades the code for the ID token of the user and verifies it
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&tokens); err != nil || res.StatusCode != http.StatusOK {
+		return nil, ErrTokenExchange
+	}
+
+	if tokens.IDToken == "" {
+		return nil, ErrNoIDToken
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// Claims of the ID token we use
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// VerifyIDToken checks the signature with the keys of the provider, the issuer, audience, expiration and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	parser := &jwt.Parser{
+		ValidMethods:         []string{"RS256", "RS384", "RS512", "ES256", "ES384"},
+		SkipClaimsValidation: true, // validated below, with leeway
+	}
+
+	token, err := parser.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).After(now.Add(leeway)) {
+		return nil, ErrInvalidIDToken
+	}
+
+	if claims["iss"] != p.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+
+	if !hasAudience(claims["aud"], p.ClientID) {
+		return nil, ErrInvalidIDToken
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	// the standard claims, decoded again into their types
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Claims
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if result.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	return &result, nil
+}
+
+// the aud claim is a string or a list of them
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// key returns the signing key of the kid, refreshing the keys when the provider rotated them
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysAt) < cacheTTL {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keysAt) < minRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := fetchKeys(ctx, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+	p.keysAt = time.Now()
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// NewVerifier returns a random PKCE code verifier and its S256 challenge
+func NewVerifier() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString returns n random bytes in base64url, for states and nonces
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s answered %d", ErrDiscoveryFailed, url, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type AccountJobRepo struct {
+	DB Conn
+}
+
+func (a *AccountJobRepo) Create(job *domain.AccountJob) (*domain.AccountJob, error) {
+	_, err := a.DB.Model(job).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (a *AccountJobRepo) GetByID(id int64) (*domain.AccountJob, error) {
+	job := new(domain.AccountJob)
+	err := a.DB.Model(job).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (a *AccountJobRepo) ListByUser(userID int64) ([]*domain.AccountJob, error) {
+	var jobs []*domain.AccountJob
+	err := a.DB.Model(&jobs).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// GetPending returns the job of the kind waiting to run, if any
+func (a *AccountJobRepo) GetPending(userID int64, kind string) (*domain.AccountJob, error) {
+	job := new(domain.AccountJob)
+	err := a.DB.Model(job).
+		Where("user_id = ?", userID).
+		Where("kind = ?", kind).
+		Where("status IN (?)", pg.In([]string{domain.AccountJobPending, domain.AccountJobRunning})).
+		First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (a *AccountJobRepo) Update(job *domain.AccountJob) (*domain.AccountJob, error) {
+	_, err := a.DB.Model(job).WherePK().Returning("*").Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Cancel cancels the job if it is still pending, false when it started running (or finished) in the meantime
+func (a *AccountJobRepo) Cancel(job *domain.AccountJob) (bool, error) {
+	res, err := a.DB.Model(job).
+		Set("status = ?", domain.AccountJobCancelled).
+		Set("updated_at = NOW()").
+		WherePK().
+		Where("status = ?", domain.AccountJobPending).
+		Returning("*").
+		Update()
+	if err != nil {
+		// nothing to return, the job isn't pending anymore
+		if errors.Is(err, pg.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+// ClaimDue marks the due jobs as running and returns them. SKIP LOCKED lets several instances share the work.
+// The jobs running for longer than the lease are claimed again, the instance running them likely died
+func (a *AccountJobRepo) ClaimDue(now time.Time, limit int) ([]*domain.AccountJob, error) {
+	var jobs []*domain.AccountJob
+	_, err := a.DB.Query(&jobs, `
+		UPDATE account_jobs SET status = ?, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM account_jobs
+			WHERE (status = ? AND run_after <= ?) OR (status = ? AND updated_at <= ?)
+			ORDER BY run_after
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, domain.AccountJobRunning,
+		domain.AccountJobPending, now,
+		domain.AccountJobRunning, now.Add(-domain.AccountJobLease),
+		limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ListExpired returns the finished exports whose file must be deleted
+func (a *AccountJobRepo) ListExpired(now time.Time) ([]*domain.AccountJob, error) {
+	var jobs []*domain.AccountJob
+	err := a.DB.Model(&jobs).
+		Where("status = ?", domain.AccountJobDone).
+		Where("expires_at <= ?", now).
+		Where("file_name <> ''").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func NewAccountJobRepo(DB Conn) *AccountJobRepo {
+	return &AccountJobRepo{DB: DB}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type ArchiveRuleRepo struct {
+	DB Conn
+}
+
+func (a *ArchiveRuleRepo) GetByUser(userID int64) (*domain.ArchiveRule, error) {
+	rule := new(domain.ArchiveRule)
+	err := a.DB.Model(rule).Where("user_id = ?", userID).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// Save inserts the rule of the user or replaces the existing one
+func (a *ArchiveRuleRepo) Save(rule *domain.ArchiveRule) (*domain.ArchiveRule, error) {
+	_, err := a.DB.Model(rule).
+		OnConflict("(user_id) DO UPDATE").
+		Set("enabled = EXCLUDED.enabled").
+		Set("completed_after_days = EXCLUDED.completed_after_days").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func NewArchiveRuleRepo(DB Conn) *ArchiveRuleRepo {
+	return &ArchiveRuleRepo{DB: DB}
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type AssignmentRepo struct {
+	DB Conn
+}
+
+func (a *AssignmentRepo) Create(assignment *domain.Assignment) (*domain.Assignment, error) {
+	_, err := a.DB.Model(assignment).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (a *AssignmentRepo) GetByID(id int64) (*domain.Assignment, error) {
+	assignment := new(domain.Assignment)
+	err := a.DB.Model(assignment).Where("assignment.id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (a *AssignmentRepo) Update(assignment *domain.Assignment) (*domain.Assignment, error) {
+	_, err := a.DB.Model(assignment).WherePK().Returning("*").Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (a *AssignmentRepo) ListPendingByAssignee(userID int64) ([]*domain.Assignment, error) {
+	var assignments []*domain.Assignment
+	err := a.DB.Model(&assignments).
+		Relation("Todo").
+		Where("assignment.assignee_id = ?", userID).
+		Where("assignment.status = ?", domain.AssignmentPending).
+		Order("assignment.created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (a *AssignmentRepo) CancelPending(todoID int64) error {
+	_, err := a.DB.Model((*domain.Assignment)(nil)).
+		Set("status = ?", domain.AssignmentCancelled).
+		Set("updated_at = NOW()").
+		Where("todo_id = ?", todoID).
+		Where("status = ?", domain.AssignmentPending).
+		Update()
+
+	return err
+}
+
+func NewAssignmentRepo(DB Conn) *AssignmentRepo {
+	return &AssignmentRepo{DB: DB}
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"todo/domain"
+)
+
+// how many entries a page of the audit log shows at most
+const auditLogsLimit = 200
+
+type AuditLogRepo struct {
+	DB Conn
+}
+
+func (a *AuditLogRepo) Create(entry *domain.AuditLog) (*domain.AuditLog, error) {
+	_, err := a.DB.Model(entry).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (a *AuditLogRepo) List(filter domain.AuditLogFilter) ([]*domain.AuditLog, error) {
+	var entries []*domain.AuditLog
+	query := a.DB.Model(&entries)
+
+	if filter.ActorID != nil {
+		query.Where("actor_id = ?", *filter.ActorID)
+	}
+
+	if filter.TargetUserID != nil {
+		query.Where("target_user_id = ?", *filter.TargetUserID)
+	}
+
+	if filter.Action != "" {
+		query.Where("action = ?", filter.Action)
+	}
+
+	err := query.
+		Order("created_at DESC", "id DESC").
+		Limit(auditLogsLimit).
+		Offset(filter.Offset).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func NewAuditLogRepo(DB Conn) *AuditLogRepo {
+	return &AuditLogRepo{DB: DB}
+}
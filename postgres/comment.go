@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"todo/domain"
+)
+
+type CommentRepo struct {
+	DB Conn
+}
+
+func (c *CommentRepo) Create(comment *domain.Comment) (*domain.Comment, error) {
+	_, err := c.DB.Model(comment).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (c *CommentRepo) ListByTodo(todoID int64) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	err := c.DB.Model(&comments).Where("todo_id = ?", todoID).Order("created_at ASC").Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (c *CommentRepo) ListByUser(userID int64) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	err := c.DB.Model(&comments).Where("user_id = ?", userID).Order("created_at ASC").Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func NewCommentRepo(DB Conn) *CommentRepo {
+	return &CommentRepo{DB: DB}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+type DependencyRepo struct {
+	DB Conn
+}
+
+// Create locks the todos of the graph of the user before reading the edges, so two requests can't
+// close a cycle together. Adding a dependency that already exists returns it
+func (d *DependencyRepo) Create(dependency *domain.TodoDependency, userID int64, createsCycle func([]*domain.DependencyEdge) bool) (*domain.TodoDependency, error) {
+	err := runInTransaction(d.DB, func(tx *pg.Tx) error {
+		_, err := tx.Exec(`SELECT id FROM todos WHERE user_id = ?0 OR assignee_id = ?0 ORDER BY id FOR UPDATE`, userID)
+		if err != nil {
+			return err
+		}
+
+		edges, err := listByUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if createsCycle(edges) {
+			return domain.ErrDependencyCycle
+		}
+
+		_, err = tx.Model(dependency).OnConflict("DO NOTHING").Returning("*").Insert()
+		if errors.Is(err, pg.ErrNoRows) {
+			return tx.Model(dependency).WherePK().Select()
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dependency, nil
+}
+
+func (d *DependencyRepo) Delete(dependency *domain.TodoDependency) error {
+	res, err := d.DB.Model(dependency).WherePK().Delete()
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return domain.ErrNoResult
+	}
+
+	return nil
+}
+
+// edges returns the dependencies joined with the blocking todo, so we know if it's completed
+func edges(db orm.DB) *orm.Query {
+	return db.Model((*domain.TodoDependency)(nil)).
+		ColumnExpr("todo_dependency.todo_id, todo_dependency.blocked_by_id, blocker.completed AS blocker_completed").
+		Join("JOIN todos AS blocker ON blocker.id = todo_dependency.blocked_by_id")
+}
+
+func (d *DependencyRepo) ListByTodo(todoID int64) ([]*domain.DependencyEdge, error) {
+	var result []*domain.DependencyEdge
+	err := edges(d.DB).Where("todo_dependency.todo_id = ?", todoID).Select(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListByUser loads the whole dependency graph of the todos the user created or is assigned to
+func (d *DependencyRepo) ListByUser(userID int64) ([]*domain.DependencyEdge, error) {
+	return listByUser(d.DB, userID)
+}
+
+func listByUser(db orm.DB, userID int64) ([]*domain.DependencyEdge, error) {
+	var result []*domain.DependencyEdge
+	err := edges(db).
+		Join("JOIN todos AS blocked ON blocked.id = todo_dependency.todo_id").
+		Where("blocked.user_id = ?0 OR blocked.assignee_id = ?0 OR blocker.user_id = ?0 OR blocker.assignee_id = ?0", userID).
+		Select(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func NewDependencyRepo(DB Conn) *DependencyRepo {
+	return &DependencyRepo{DB: DB}
+}
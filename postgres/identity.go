@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type IdentityRepo struct {
+	DB Conn
+}
+
+func (i *IdentityRepo) Create(identity *domain.UserIdentity) (*domain.UserIdentity, error) {
+	_, err := i.DB.Model(identity).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (i *IdentityRepo) GetByID(id int64) (*domain.UserIdentity, error) {
+	identity := new(domain.UserIdentity)
+	err := i.DB.Model(identity).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (i *IdentityRepo) GetByProviderSubject(provider, subject string) (*domain.UserIdentity, error) {
+	identity := new(domain.UserIdentity)
+	err := i.DB.Model(identity).
+		Where("provider = ?", provider).
+		Where("subject = ?", subject).
+		First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (i *IdentityRepo) ListByUser(userID int64) ([]*domain.UserIdentity, error) {
+	var identities []*domain.UserIdentity
+	err := i.DB.Model(&identities).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (i *IdentityRepo) Delete(identity *domain.UserIdentity) error {
+	_, err := i.DB.Model(identity).WherePK().Delete()
+	return err
+}
+
+func (i *IdentityRepo) CreateLoginState(state *domain.OIDCLoginState) (*domain.OIDCLoginState, error) {
+	_, err := i.DB.Model(state).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// ConsumeLoginState deletes the state and returns it, so a callback can't be replayed
+func (i *IdentityRepo) ConsumeLoginState(hash string) (*domain.OIDCLoginState, error) {
+	state := new(domain.OIDCLoginState)
+	res, err := i.DB.Model(state).
+		Where("state_hash = ?", hash).
+		Where("expires_at > NOW()").
+		Returning("*").
+		Delete()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return nil, domain.ErrNoResult
+	}
+
+	return state, nil
+}
+
+// DeleteExpiredLoginStates forgets the logins that were never finished. It runs as a background job
+func (i *IdentityRepo) DeleteExpiredLoginStates(now time.Time) error {
+	_, err := i.DB.Model((*domain.OIDCLoginState)(nil)).Where("expires_at <= ?", now).Delete()
+	return err
+}
+
+func NewIdentityRepo(DB Conn) *IdentityRepo {
+	return &IdentityRepo{DB: DB}
+}
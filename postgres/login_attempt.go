@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type loginAttempt struct {
+	tableName struct{} `pg:"login_attempts"`
+
+	Key         string `pg:",pk"`
+	Failures    int    `pg:",use_zero"`
+	LastFailure time.Time
+}
+
+// LoginAttemptStore is the domain.LoginAttemptStore shared by every instance of the API
+type LoginAttemptStore struct {
+	DB *pg.DB
+}
+
+func (l *LoginAttemptStore) Get(key string) (*domain.LoginAttempts, error) {
+	attempt := new(loginAttempt)
+	err := l.DB.Model(attempt).
+		Where("key = ?", key).
+		Where("last_failure > ?", time.Now().Add(-domain.LoginAttemptsWindow)).
+		Select()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &domain.LoginAttempts{Failures: attempt.Failures, LastFailure: attempt.LastFailure}, nil
+}
+
+// Fail counts the failure in a single statement, the counter restarts when the last failure is out of the window
+func (l *LoginAttemptStore) Fail(key string, now time.Time) (*domain.LoginAttempts, error) {
+	attempt := &loginAttempt{Key: key, Failures: 1, LastFailure: now}
+	_, err := l.DB.Model(attempt).
+		OnConflict("(key) DO UPDATE").
+		Set("failures = CASE WHEN login_attempt.last_failure > ? THEN login_attempt.failures + 1 ELSE 1 END",
+			now.Add(-domain.LoginAttemptsWindow)).
+		Set("last_failure = EXCLUDED.last_failure").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginAttempts{Failures: attempt.Failures, LastFailure: attempt.LastFailure}, nil
+}
+
+func (l *LoginAttemptStore) Reset(key string) error {
+	_, err := l.DB.Model((*loginAttempt)(nil)).Where("key = ?", key).Delete()
+	return err
+}
+
+// DeleteExpired forgets the failures out of the window. It runs as a background job
+func (l *LoginAttemptStore) DeleteExpired(now time.Time) error {
+	_, err := l.DB.Model((*loginAttempt)(nil)).
+		Where("last_failure <= ?", now.Add(-domain.LoginAttemptsWindow)).
+		Delete()
+	return err
+}
+
+func NewLoginAttemptStore(DB *pg.DB) *LoginAttemptStore {
+	return &LoginAttemptStore{DB: DB}
+}
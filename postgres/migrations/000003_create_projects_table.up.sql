@@ -0,0 +1,13 @@
+CREATE TABLE projects
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+
+);
+
+ALTER TABLE todos ADD COLUMN project_id BIGINT REFERENCES projects (id) ON DELETE SET NULL;
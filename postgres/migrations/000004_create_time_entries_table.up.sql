@@ -0,0 +1,20 @@
+CREATE TABLE time_entries
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+
+    todo_id BIGINT REFERENCES todos (id) ON DELETE CASCADE NOT NULL,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    stopped_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CHECK (stopped_at IS NULL OR stopped_at >= started_at)
+);
+
+-- a user can only have one running timer (i.e, an entry without stopped_at)
+CREATE UNIQUE INDEX time_entries_one_running_per_user ON time_entries (user_id) WHERE stopped_at IS NULL;
+CREATE INDEX time_entries_user_id_started_at ON time_entries (user_id, started_at);
@@ -0,0 +1,4 @@
+ALTER TABLE projects ADD COLUMN workflow JSONB;
+
+ALTER TABLE todos ADD COLUMN status VARCHAR(64) NOT NULL DEFAULT 'todo';
+UPDATE todos SET status = 'done' WHERE completed;
@@ -0,0 +1,13 @@
+-- todo_id can't be completed until blocked_by_id is done
+CREATE TABLE todo_dependencies
+(
+    todo_id BIGINT REFERENCES todos (id) ON DELETE CASCADE NOT NULL,
+    blocked_by_id BIGINT REFERENCES todos (id) ON DELETE CASCADE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (todo_id, blocked_by_id),
+    CHECK (todo_id <> blocked_by_id)
+);
+
+CREATE INDEX todo_dependencies_blocked_by_id ON todo_dependencies (blocked_by_id);
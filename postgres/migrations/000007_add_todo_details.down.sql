@@ -0,0 +1,6 @@
+ALTER TABLE todos DROP COLUMN IF EXISTS recurrence;
+ALTER TABLE todos DROP COLUMN IF EXISTS due_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS priority;
+ALTER TABLE todos DROP COLUMN IF EXISTS tags;
+
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE todos ADD COLUMN tags TEXT[] DEFAULT '{}';
+ALTER TABLE todos ADD COLUMN priority VARCHAR(16) DEFAULT '';
+ALTER TABLE todos ADD COLUMN due_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN recurrence VARCHAR(255) DEFAULT '';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS archive_rules;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS unarchived_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS completed_at;
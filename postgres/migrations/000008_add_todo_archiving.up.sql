@@ -0,0 +1,14 @@
+ALTER TABLE todos ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN unarchived_at TIMESTAMP WITH TIME ZONE;
+UPDATE todos SET completed_at = updated_at WHERE completed;
+
+CREATE TABLE archive_rules
+(
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    completed_after_days INTEGER NOT NULL CHECK (completed_after_days > 0),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN snoozed_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX todos_snoozed_until ON todos (snoozed_until) WHERE snoozed_until IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS assignments;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS assignee_id;
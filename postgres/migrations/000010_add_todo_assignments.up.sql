@@ -0,0 +1,22 @@
+-- user_id stays the creator of the todo, assignee_id is the user that accepted to work on it
+ALTER TABLE todos ADD COLUMN assignee_id BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX todos_assignee_id ON todos (assignee_id) WHERE assignee_id IS NOT NULL;
+
+CREATE TABLE assignments
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+
+    todo_id BIGINT REFERENCES todos (id) ON DELETE CASCADE NOT NULL,
+    assignee_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    assigned_by_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- a todo has at most one assignment waiting for an answer
+CREATE UNIQUE INDEX assignments_one_pending_per_todo ON assignments (todo_id) WHERE status = 'pending';
+CREATE INDEX assignments_assignee_id ON assignments (assignee_id, status);
@@ -0,0 +1,29 @@
+CREATE TABLE comments
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    body TEXT NOT NULL,
+
+    todo_id BIGINT REFERENCES todos (id) ON DELETE CASCADE NOT NULL,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX comments_todo_id ON comments (todo_id);
+
+CREATE TABLE notifications
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    todo_id BIGINT REFERENCES todos (id) ON DELETE CASCADE,
+
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notifications_user_id_unread ON notifications (user_id, created_at) WHERE read_at IS NULL;
+CREATE INDEX notifications_user_id ON notifications (user_id, created_at);
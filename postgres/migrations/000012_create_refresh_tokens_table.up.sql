@@ -0,0 +1,17 @@
+CREATE TABLE refresh_tokens
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- every token obtained by rotating another one shares its family
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,11 @@
+-- bumping the generation invalidates every access token issued before ("log out everywhere")
+ALTER TABLE users ADD COLUMN token_generation BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE revoked_tokens
+(
+    jti VARCHAR(64) PRIMARY KEY,
+    -- once the token expires we don't need to remember it
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- single use tokens sent to the users (email verification, ...). Only the hash is stored
+CREATE TABLE user_tokens
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    -- what the token was issued for, e.g the email address to verify
+    data VARCHAR(255) NOT NULL DEFAULT '',
+
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+
+    expires_at TIMESTAMP WITH TIME ZONE,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX user_tokens_user_id_purpose ON user_tokens (user_id, purpose);
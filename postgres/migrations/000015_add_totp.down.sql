@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_secret;
+
+DELETE FROM user_tokens WHERE purpose = 'mfa_recovery';
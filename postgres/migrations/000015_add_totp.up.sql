@@ -0,0 +1,5 @@
+-- the secret is encrypted with MFA_ENCRYPTION_KEY, it's set on enrollment and enabled once confirmed with a code
+ALTER TABLE users ADD COLUMN mfa_secret TEXT;
+ALTER TABLE users ADD COLUMN mfa_enabled_at TIMESTAMP WITH TIME ZONE;
+-- last accepted time step, so a code can't be used twice
+ALTER TABLE users ADD COLUMN mfa_last_step BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+CREATE TABLE passkeys
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+
+    credential_id BYTEA NOT NULL UNIQUE,
+    -- COSE_Key of the credential
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[],
+    aaguid BYTEA,
+
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX passkeys_user_id ON passkeys (user_id);
@@ -0,0 +1,29 @@
+-- pending logins with an OpenID provider, between the redirect and the callback
+CREATE TABLE oidc_login_states
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    state_hash VARCHAR(64) NOT NULL UNIQUE,
+    provider VARCHAR(64) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    -- set when a logged in user links the provider to the account
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE,
+
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE user_identities
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,18 @@
+-- the first admin is promoted by hand: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
+
+-- every action of the admins, kept when the users involved are deleted
+CREATE TABLE audit_logs
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    actor_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    action VARCHAR(64) NOT NULL,
+    target_user_id BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_logs_target_user_id ON audit_logs (target_user_id);
+CREATE INDEX audit_logs_created_at ON audit_logs (created_at);
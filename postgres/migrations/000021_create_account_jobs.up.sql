@@ -0,0 +1,20 @@
+-- data exports and account deletions, run in the background
+CREATE TABLE account_jobs
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    -- deletions wait for the grace period
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+
+    finished_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX account_jobs_user_id ON account_jobs (user_id);
+CREATE INDEX account_jobs_pending ON account_jobs (run_after) WHERE status = 'pending';
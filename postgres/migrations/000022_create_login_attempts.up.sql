@@ -0,0 +1,9 @@
+-- failed logins by account ("account:<email>") and by client address ("ip:<address>")
+CREATE TABLE login_attempts
+(
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX login_attempts_last_failure ON login_attempts (last_failure);
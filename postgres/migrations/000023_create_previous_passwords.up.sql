@@ -0,0 +1,10 @@
+-- hashes of the last passwords of the users, they can't be reused (see the password policy)
+CREATE TABLE previous_passwords
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX previous_passwords_user_id ON previous_passwords (user_id, id);
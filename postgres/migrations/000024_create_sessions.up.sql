@@ -0,0 +1,16 @@
+-- a login on a device, it lasts as long as its refresh token family
+CREATE TABLE sessions
+(
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    family_id VARCHAR(64) NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    device_name VARCHAR(100) NOT NULL DEFAULT '',
+
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sessions_user_id ON sessions (user_id, last_seen_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_username_lower;
+DROP INDEX IF EXISTS users_email_lower;
@@ -0,0 +1,4 @@
+-- emails and usernames are unique ignoring case, run the identity-collisions command first:
+-- the indexes can't be created while two users collide
+CREATE UNIQUE INDEX users_email_lower ON users (LOWER(email));
+CREATE UNIQUE INDEX users_username_lower ON users (LOWER(username));
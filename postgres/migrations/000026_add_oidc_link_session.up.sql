@@ -0,0 +1,2 @@
+-- the link of a provider must be finished from the session that began it
+ALTER TABLE oidc_login_states ADD COLUMN session_id BIGINT REFERENCES sessions (id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_email_key_idx;
+DROP INDEX IF EXISTS users_username_key_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS username_key,
+    DROP COLUMN IF EXISTS email_key;
@@ -0,0 +1,10 @@
+-- the keys of domain.UsernameKey and domain.EmailKey: usernames and emails that can't be told apart
+-- (case folding, NFKC, confusable characters) can't be taken twice. They can't be computed in SQL,
+-- the app fills them on startup and makes them NOT NULL (postgres.BackfillUserKeys)
+ALTER TABLE users
+    ADD COLUMN username_key VARCHAR(255),
+    ADD COLUMN email_key VARCHAR(255);
+
+-- users_username_key and users_email_key are the UNIQUE constraints of the users table
+CREATE UNIQUE INDEX users_username_key_idx ON users (username_key);
+CREATE UNIQUE INDEX users_email_key_idx ON users (email_key);
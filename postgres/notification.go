@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// how many notifications the inbox shows
+const notificationsLimit = 100
+
+type NotificationRepo struct {
+	DB Conn
+}
+
+func (n *NotificationRepo) Create(notification *domain.Notification) (*domain.Notification, error) {
+	_, err := n.DB.Model(notification).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (n *NotificationRepo) GetByID(id int64) (*domain.Notification, error) {
+	notification := new(domain.Notification)
+	err := n.DB.Model(notification).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (n *NotificationRepo) ListByUser(userID int64) ([]*domain.Notification, error) {
+	var notifications []*domain.Notification
+	err := n.DB.Model(&notifications).
+		Where("user_id = ?", userID).
+		OrderExpr("read_at IS NULL DESC, created_at DESC").
+		Limit(notificationsLimit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (n *NotificationRepo) CountUnread(userID int64) (int, error) {
+	return n.DB.Model((*domain.Notification)(nil)).
+		Where("user_id = ?", userID).
+		Where("read_at IS NULL").
+		Count()
+}
+
+func (n *NotificationRepo) MarkRead(notification *domain.Notification) (*domain.Notification, error) {
+	_, err := n.DB.Model(notification).
+		Set("read_at = NOW()").
+		WherePK().
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (n *NotificationRepo) MarkAllRead(userID int64) error {
+	_, err := n.DB.Model((*domain.Notification)(nil)).
+		Set("read_at = NOW()").
+		Where("user_id = ?", userID).
+		Where("read_at IS NULL").
+		Update()
+
+	return err
+}
+
+func NewNotificationRepo(DB Conn) *NotificationRepo {
+	return &NotificationRepo{DB: DB}
+}
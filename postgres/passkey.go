@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type PasskeyRepo struct {
+	DB Conn
+}
+
+func (p *PasskeyRepo) Create(passkey *domain.Passkey) (*domain.Passkey, error) {
+	_, err := p.DB.Model(passkey).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (p *PasskeyRepo) GetByID(id int64) (*domain.Passkey, error) {
+	passkey := new(domain.Passkey)
+	err := p.DB.Model(passkey).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (p *PasskeyRepo) GetByCredentialID(credentialID []byte) (*domain.Passkey, error) {
+	passkey := new(domain.Passkey)
+	err := p.DB.Model(passkey).Where("credential_id = ?", credentialID).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (p *PasskeyRepo) ListByUser(userID int64) ([]*domain.Passkey, error) {
+	var passkeys []*domain.Passkey
+	err := p.DB.Model(&passkeys).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+func (p *PasskeyRepo) UpdateSignCount(passkey *domain.Passkey) error {
+	_, err := p.DB.Model(passkey).
+		Column("sign_count", "last_used_at").
+		WherePK().
+		Update()
+
+	return err
+}
+
+func (p *PasskeyRepo) Delete(passkey *domain.Passkey) error {
+	_, err := p.DB.Model(passkey).WherePK().Delete()
+	return err
+}
+
+func NewPasskeyRepo(DB Conn) *PasskeyRepo {
+	return &PasskeyRepo{DB: DB}
+}
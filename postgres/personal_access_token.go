@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type PersonalAccessTokenRepo struct {
+	DB Conn
+}
+
+func (p *PersonalAccessTokenRepo) Create(token *domain.PersonalAccessToken) (*domain.PersonalAccessToken, error) {
+	_, err := p.DB.Model(token).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (p *PersonalAccessTokenRepo) GetByID(id int64) (*domain.PersonalAccessToken, error) {
+	token := new(domain.PersonalAccessToken)
+	err := p.DB.Model(token).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (p *PersonalAccessTokenRepo) GetByHash(hash string) (*domain.PersonalAccessToken, error) {
+	token := new(domain.PersonalAccessToken)
+	err := p.DB.Model(token).Where("token_hash = ?", hash).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (p *PersonalAccessTokenRepo) ListByUser(userID int64) ([]*domain.PersonalAccessToken, error) {
+	var tokens []*domain.PersonalAccessToken
+	err := p.DB.Model(&tokens).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (p *PersonalAccessTokenRepo) Update(token *domain.PersonalAccessToken) (*domain.PersonalAccessToken, error) {
+	_, err := p.DB.Model(token).WherePK().Returning("*").Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (p *PersonalAccessTokenRepo) TouchLastUsed(token *domain.PersonalAccessToken) error {
+	_, err := p.DB.Model(token).
+		Set("last_used_at = NOW()").
+		WherePK().
+		Returning("last_used_at").
+		Update()
+
+	return err
+}
+
+func (p *PersonalAccessTokenRepo) Delete(token *domain.PersonalAccessToken) error {
+	_, err := p.DB.Model(token).WherePK().Delete()
+	return err
+}
+
+func (p *PersonalAccessTokenRepo) DeleteByUser(userID int64) error {
+	_, err := p.DB.Model((*domain.PersonalAccessToken)(nil)).Where("user_id = ?", userID).Delete()
+	return err
+}
+
+func NewPersonalAccessTokenRepo(DB Conn) *PersonalAccessTokenRepo {
+	return &PersonalAccessTokenRepo{DB: DB}
+}
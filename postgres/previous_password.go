@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"todo/domain"
+)
+
+type PreviousPasswordRepo struct {
+	DB Conn
+}
+
+func (p *PreviousPasswordRepo) Create(password *domain.PreviousPassword) (*domain.PreviousPassword, error) {
+	_, err := p.DB.Model(password).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return password, nil
+}
+
+// ListRecent returns the newest first
+func (p *PreviousPasswordRepo) ListRecent(userID int64, limit int) ([]*domain.PreviousPassword, error) {
+	var passwords []*domain.PreviousPassword
+	err := p.DB.Model(&passwords).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return passwords, nil
+}
+
+func (p *PreviousPasswordRepo) DeleteOlder(userID int64, keep int) error {
+	_, err := p.DB.Model((*domain.PreviousPassword)(nil)).
+		Where("user_id = ?", userID).
+		Where("id NOT IN (SELECT id FROM previous_passwords WHERE user_id = ? ORDER BY id DESC LIMIT ?)", userID, keep).
+		Delete()
+	return err
+}
+
+func NewPreviousPasswordRepo(DB Conn) *PreviousPasswordRepo {
+	return &PreviousPasswordRepo{DB: DB}
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type ProjectRepo struct {
+	DB Conn
+}
+
+func (p *ProjectRepo) Create(project *domain.Project) (*domain.Project, error) {
+	_, err := p.DB.Model(project).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *ProjectRepo) GetByID(id int64) (*domain.Project, error) {
+	project := new(domain.Project)
+	err := p.DB.Model(project).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *ProjectRepo) Update(project *domain.Project) (*domain.Project, error) {
+	_, err := p.DB.Model(project).Where("id = ?", project.ID).Returning("*").Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *ProjectRepo) ListByUser(userID int64) ([]*domain.Project, error) {
+	var projects []*domain.Project
+	err := p.DB.Model(&projects).Where("user_id = ?", userID).Order("name ASC").Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func NewProjectRepo(DB Conn) *ProjectRepo {
+	return &ProjectRepo{DB: DB}
+}
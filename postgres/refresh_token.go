@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type RefreshTokenRepo struct {
+	DB Conn
+}
+
+func (r *RefreshTokenRepo) Create(token *domain.RefreshToken) (*domain.RefreshToken, error) {
+	_, err := r.DB.Model(token).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r *RefreshTokenRepo) GetByHash(hash string) (*domain.RefreshToken, error) {
+	token := new(domain.RefreshToken)
+	err := r.DB.Model(token).Where("token_hash = ?", hash).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// MarkUsed returns false when the token was already used (e.g by a concurrent request)
+func (r *RefreshTokenRepo) MarkUsed(token *domain.RefreshToken) (bool, error) {
+	res, err := r.DB.Model(token).
+		Set("used_at = NOW()").
+		WherePK().
+		Where("used_at IS NULL").
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Update()
+	if err != nil {
+		// nothing to return, the token was used or revoked
+		if errors.Is(err, pg.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+func (r *RefreshTokenRepo) RevokeFamily(familyID string) error {
+	_, err := r.DB.Model((*domain.RefreshToken)(nil)).
+		Set("revoked_at = NOW()").
+		Where("family_id = ?", familyID).
+		Where("revoked_at IS NULL").
+		Update()
+
+	return err
+}
+
+func (r *RefreshTokenRepo) RevokeByUser(userID int64) error {
+	_, err := r.DB.Model((*domain.RefreshToken)(nil)).
+		Set("revoked_at = NOW()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Update()
+
+	return err
+}
+
+func NewRefreshTokenRepo(DB Conn) *RefreshTokenRepo {
+	return &RefreshTokenRepo{DB: DB}
+}
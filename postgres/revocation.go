@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type revokedToken struct {
+	tableName struct{} `pg:"revoked_tokens"`
+
+	JTI       string `pg:"jti,pk"`
+	ExpiresAt time.Time
+}
+
+// RevocationStore is the domain.RevocationStore shared by every instance of the API
+type RevocationStore struct {
+	DB *pg.DB
+}
+
+func (r *RevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	_, err := r.DB.Model(&revokedToken{JTI: jti, ExpiresAt: expiresAt}).OnConflict("DO NOTHING").Insert()
+	return err
+}
+
+func (r *RevocationStore) IsRevoked(jti string) (bool, error) {
+	return r.DB.Model((*revokedToken)(nil)).
+		Where("jti = ?", jti).
+		Where("expires_at > NOW()").
+		Exists()
+}
+
+// DeleteExpired forgets the tokens that expired anyway. It runs as a background job
+func (r *RevocationStore) DeleteExpired(now time.Time) error {
+	_, err := r.DB.Model((*revokedToken)(nil)).Where("expires_at <= ?", now).Delete()
+	return err
+}
+
+func NewRevocationStore(DB *pg.DB) *RevocationStore {
+	return &RevocationStore{DB: DB}
+}
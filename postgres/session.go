@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+)
+
+type SessionRepo struct {
+	DB Conn
+}
+
+func (s *SessionRepo) Create(session *domain.Session) (*domain.Session, error) {
+	_, err := s.DB.Model(session).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SessionRepo) GetByID(id int64) (*domain.Session, error) {
+	session := new(domain.Session)
+	err := s.DB.Model(session).Where("id = ?", id).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SessionRepo) GetByFamilyID(familyID string) (*domain.Session, error) {
+	session := new(domain.Session)
+	err := s.DB.Model(session).Where("family_id = ?", familyID).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SessionRepo) ListActiveByUser(userID int64, since time.Time) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+	err := s.DB.Model(&sessions).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Where("last_seen_at > ?", since).
+		Order("last_seen_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionRepo) KnownDevices(userID int64) ([]string, error) {
+	var devices []string
+	err := s.DB.Model((*domain.Session)(nil)).
+		ColumnExpr("DISTINCT device_name").
+		Where("user_id = ?", userID).
+		Select(&devices)
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (s *SessionRepo) Touch(session *domain.Session) error {
+	_, err := s.DB.Model(session).
+		Set("last_seen_at = ?last_seen_at").
+		Set("ip = ?ip").
+		WherePK().
+		Update()
+
+	return err
+}
+
+// Revoke does nothing when the session is already revoked
+func (s *SessionRepo) Revoke(session *domain.Session) error {
+	_, err := s.DB.Model(session).
+		Set("revoked_at = NOW()").
+		WherePK().
+		Where("revoked_at IS NULL").
+		Update()
+
+	return err
+}
+
+func (s *SessionRepo) RevokeByUser(userID int64) error {
+	_, err := s.DB.Model((*domain.Session)(nil)).
+		Set("revoked_at = NOW()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Update()
+
+	return err
+}
+
+func (s *SessionRepo) DeleteInactive(before time.Time) error {
+	_, err := s.DB.Model((*domain.Session)(nil)).Where("last_seen_at <= ?", before).Delete()
+	return err
+}
+
+func NewSessionRepo(DB Conn) *SessionRepo {
+	return &SessionRepo{DB: DB}
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// name of the partial unique index of the migration that allows a single running timer per user
+const oneRunningTimerIndex = "time_entries_one_running_per_user"
+
+// seconds of an entry; a running timer counts until now
+const timeEntrySecondsExpr = "EXTRACT(EPOCH FROM COALESCE(time_entry.stopped_at, NOW()) - time_entry.started_at)"
+
+type TimeEntryRepo struct {
+	DB Conn
+}
+
+func (t *TimeEntryRepo) Create(entry *domain.TimeEntry) (*domain.TimeEntry, error) {
+	_, err := t.DB.Model(entry).Returning("*").Insert()
+	if err != nil {
+		var pgErr pg.Error
+		if errors.As(err, &pgErr) && pgErr.IntegrityViolation() && pgErr.Field('n') == oneRunningTimerIndex {
+			return nil, domain.ErrTimerAlreadyRunning
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (t *TimeEntryRepo) Update(entry *domain.TimeEntry) (*domain.TimeEntry, error) {
+	_, err := t.DB.Model(entry).Where("id = ?", entry.ID).Returning("*").Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (t *TimeEntryRepo) GetRunning(userID int64) (*domain.TimeEntry, error) {
+	entry := new(domain.TimeEntry)
+	err := t.DB.Model(entry).Where("user_id = ?", userID).Where("stopped_at IS NULL").First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (t *TimeEntryRepo) ListByTodo(todoID int64) ([]*domain.TimeEntry, error) {
+	var entries []*domain.TimeEntry
+	err := t.DB.Model(&entries).Where("todo_id = ?", todoID).Order("started_at ASC").Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (t *TimeEntryRepo) ListByUser(filter domain.TimeEntryFilter) ([]*domain.TimeEntry, error) {
+	var entries []*domain.TimeEntry
+	q := t.DB.Model(&entries).Relation("Todo").Order("time_entry.started_at ASC")
+	err := applyTimeEntryFilter(q, filter).Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (t *TimeEntryRepo) Totals(filter domain.TimeEntryFilter, groupBy domain.TimeReportGroup) ([]*domain.TimeReportRow, error) {
+	var rows []*domain.TimeReportRow
+
+	q := t.DB.Model((*domain.TimeEntry)(nil)).
+		ColumnExpr("SUM(" + timeEntrySecondsExpr + ")::BIGINT AS seconds").
+		Join("JOIN todos ON todos.id = time_entry.todo_id")
+
+	switch groupBy {
+	case domain.TimeReportByTodo:
+		q = q.ColumnExpr("time_entry.todo_id, todos.title AS todo_title").
+			Group("time_entry.todo_id", "todos.title").
+			Order("seconds DESC")
+	case domain.TimeReportByProject:
+		q = q.ColumnExpr("todos.project_id").
+			Group("todos.project_id").
+			Order("seconds DESC")
+	case domain.TimeReportByDay:
+		timezone := "UTC"
+		if filter.Location != nil {
+			timezone = filter.Location.String()
+		}
+
+		q = q.ColumnExpr("TO_CHAR(time_entry.started_at AT TIME ZONE ?, 'YYYY-MM-DD') AS day", timezone).
+			GroupExpr("day").
+			Order("day ASC")
+	}
+
+	err := applyTimeEntryFilter(q, filter).Select(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func applyTimeEntryFilter(q *orm.Query, filter domain.TimeEntryFilter) *orm.Query {
+	q = q.Where("time_entry.user_id = ?", filter.UserID)
+
+	if !filter.From.IsZero() {
+		q = q.Where("time_entry.started_at >= ?", filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		q = q.Where("time_entry.started_at < ?", filter.To)
+	}
+
+	return q
+}
+
+func NewTimeEntryRepo(DB Conn) *TimeEntryRepo {
+	return &TimeEntryRepo{DB: DB}
+}
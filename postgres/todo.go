@@ -1,13 +1,12 @@
 package postgres
 
 import (
+	"time"
 	"todo/domain"
-
-	"github.com/go-pg/pg/v10"
 )
 
 type TodoRepo struct {
-	DB *pg.DB
+	DB Conn
 }
 
 func (t *TodoRepo) GetByID(id int64) (*domain.Todo, error) {
@@ -40,7 +39,87 @@ func (t *TodoRepo) Delete(todo *domain.Todo) error {
 	return nil
 }
 
-func NewTodoRepo(DB *pg.DB) *TodoRepo {
+func (t *TodoRepo) List(filter domain.TodoFilter) ([]*domain.Todo, error) {
+	var todos []*domain.Todo
+	q := t.DB.Model(&todos).Order("created_at DESC")
+
+	switch filter.View {
+	case domain.TodoViewCreated:
+		q = q.Where("user_id = ?", filter.UserID)
+	case domain.TodoViewAssigned:
+		q = q.Where("assignee_id = ?", filter.UserID)
+	default:
+		q = q.Where("user_id = ?0 OR assignee_id = ?0", filter.UserID)
+	}
+
+	if filter.ProjectID != nil {
+		q = q.Where("project_id = ?", *filter.ProjectID)
+	}
+
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+
+	if filter.Completed != nil {
+		q = q.Where("completed = ?", *filter.Completed)
+	}
+
+	if filter.OnlyArchived {
+		q = q.Where("archived_at IS NOT NULL")
+	} else if !filter.IncludeArchived {
+		q = q.Where("archived_at IS NULL")
+	}
+
+	if !filter.IncludeSnoozed {
+		q = q.Where("snoozed_until IS NULL OR snoozed_until <= NOW()")
+	}
+
+	err := q.Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+func (t *TodoRepo) ArchiveCompleted(now time.Time) (int, error) {
+	// the age counts from completion, or from the last unarchive so we don't archive a todo right after a user took it out
+	res, err := t.DB.Exec(`
+		UPDATE todos SET archived_at = ?0, updated_at = ?0
+		FROM archive_rules
+		WHERE archive_rules.user_id = todos.user_id
+			AND archive_rules.enabled
+			AND todos.completed
+			AND todos.archived_at IS NULL
+			AND GREATEST(todos.completed_at, todos.unarchived_at) <= ?0 - archive_rules.completed_after_days * INTERVAL '1 day'`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+func (t *TodoRepo) ListSnoozed(now time.Time) ([]*domain.Todo, error) {
+	var todos []*domain.Todo
+	err := t.DB.Model(&todos).Where("snoozed_until <= ?", now).Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// Wake only touches snoozed_until, so an edit made since the todo was loaded is kept
+func (t *TodoRepo) Wake(todoID int64, now time.Time) (bool, error) {
+	res, err := t.DB.Exec(`UPDATE todos SET snoozed_until = NULL WHERE id = ? AND snoozed_until <= ?`, todoID, now)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+func NewTodoRepo(DB Conn) *TodoRepo {
 	return &TodoRepo{DB: DB}
 }
 
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Conn is the connection pool or a transaction, the repos work on both
+type Conn interface {
+	orm.DB
+	Context() context.Context
+	RunInTransaction(ctx context.Context, fn func(*pg.Tx) error) error
+}
+
+// Repos returns every repo of the domain on the connection
+func Repos(DB Conn) domain.DB {
+	return domain.DB{
+		UserRepo:         NewUserRepo(DB),
+		TodoRepo:         NewTodoRepo(DB),
+		ProjectRepo:      NewProjectRepo(DB),
+		TimeEntryRepo:    NewTimeEntryRepo(DB),
+		DependencyRepo:   NewDependencyRepo(DB),
+		ArchiveRuleRepo:  NewArchiveRuleRepo(DB),
+		AssignmentRepo:   NewAssignmentRepo(DB),
+		CommentRepo:      NewCommentRepo(DB),
+		NotificationRepo: NewNotificationRepo(DB),
+		RefreshTokenRepo: NewRefreshTokenRepo(DB),
+		UserTokenRepo:    NewUserTokenRepo(DB),
+		PasskeyRepo:      NewPasskeyRepo(DB),
+		IdentityRepo:     NewIdentityRepo(DB),
+
+		PersonalAccessTokenRepo: NewPersonalAccessTokenRepo(DB),
+		AuditLogRepo:            NewAuditLogRepo(DB),
+		AccountJobRepo:          NewAccountJobRepo(DB),
+		PreviousPasswordRepo:    NewPreviousPasswordRepo(DB),
+		SessionRepo:             NewSessionRepo(DB),
+
+		Transactor: &Transactor{DB: DB},
+	}
+}
+
+// Transactor gives the repos of a transaction to the domain. Inside a transaction the calls
+// join it, see runInTransaction
+type Transactor struct {
+	DB Conn
+}
+
+func (t *Transactor) RunInTransaction(fn func(db domain.DB) error) error {
+	return runInTransaction(t.DB, func(tx *pg.Tx) error {
+		return fn(Repos(tx))
+	})
+}
+
+// runInTransaction runs fn in a new transaction, or in the one DB already is: the RunInTransaction of
+// a pg.Tx would commit (or roll back) the outer transaction as soon as fn returns
+func runInTransaction(DB Conn, fn func(*pg.Tx) error) error {
+	if tx, ok := DB.(*pg.Tx); ok {
+		return fn(tx)
+	}
+
+	return DB.RunInTransaction(DB.Context(), fn)
+}
@@ -2,15 +2,26 @@ package postgres
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"todo/domain"
 
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// names of the unique indexes of the migrations that ignore the case, and of the keys
+const (
+	usersEmailLowerIndex    = "users_email_lower"
+	usersUsernameLowerIndex = "users_username_lower"
+	usersEmailKeyIndex      = "users_email_key_idx"
+	usersUsernameKeyIndex   = "users_username_key_idx"
 )
 
 // Once we created the interface that we want the user to follow, we create our struct type
 // UserRepo which is a DB type.
 type UserRepo struct {
-	DB *pg.DB
+	DB Conn
 }
 
 func (u *UserRepo) GetByEmail(email string) (*domain.User, error) {
@@ -22,7 +33,7 @@ func (u *UserRepo) GetByEmail(email string) (*domain.User, error) {
 	// Now the interesting part, we will use SQL queries to extract the model
 	// using golang by using the DB userrepo field.
 
-	err := u.DB.Model(user).Where("email = ?", email).First()
+	err := u.DB.Model(user).Where("LOWER(email) = LOWER(?)", email).First()
 	if err != nil {
 		// errors.Is will check if err is pg.ErrNoRows
 
@@ -36,7 +47,19 @@ func (u *UserRepo) GetByEmail(email string) (*domain.User, error) {
 }
 func (u *UserRepo) GetByUsername(username string) (*domain.User, error) {
 	user := new(domain.User)
-	err := u.DB.Model(user).Where("username = ?", username).First()
+	err := u.DB.Model(user).Where("LOWER(username) = LOWER(?)", username).First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *UserRepo) GetByUsernameKey(key string) (*domain.User, error) {
+	user := new(domain.User)
+	err := u.DB.Model(user).Where("username_key = ?", key).First()
 	if err != nil {
 		if errors.Is(err, pg.ErrNoRows) {
 			return nil, domain.ErrNoResult
@@ -59,16 +82,244 @@ func (u *UserRepo) GetByID(id int64) (*domain.User, error) {
 }
 
 func (u *UserRepo) Create(user *domain.User) (*domain.User, error) {
+	setUserKeys(user)
 	//							return everything as sql
 	_, err := u.DB.Model(user).Returning("*").Insert()
 	// because we have a pointer, we need to be careful that it's not injecting the pointer
 	if err != nil {
+		return nil, userUniqueViolation(err)
+	}
+	return user, nil
+}
 
+// Update writes the profile. The user was loaded at the start of the request, the other columns
+// (disabled_at, role, the password...) may have changed since and have their own methods
+func (u *UserRepo) Update(user *domain.User) (*domain.User, error) {
+	setUserKeys(user)
+	_, err := u.DB.Model(user).
+		Column("username", "username_key", "display_name", "timezone", "updated_at").
+		WherePK().
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, userUniqueViolation(err)
+	}
+	return user, nil
+}
+
+func (u *UserRepo) UpdateEmail(user *domain.User) (*domain.User, error) {
+	setUserKeys(user)
+	_, err := u.DB.Model(user).
+		Column("email", "email_key", "email_verified_at", "updated_at").
+		WherePK().
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, userUniqueViolation(err)
+	}
+	return user, nil
+}
+
+// MarkEmailVerified verifies the email only if the user still has it
+func (u *UserRepo) MarkEmailVerified(user *domain.User) (*domain.User, error) {
+	_, err := u.DB.Model(user).
+		Set("email_verified_at = ?email_verified_at").
+		Set("updated_at = NOW()").
+		WherePK().
+		Where("email = ?email").
+		Returning("*").
+		Update()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *UserRepo) UpdateMFA(user *domain.User) error {
+	_, err := u.DB.Model(user).
+		Column("mfa_secret", "mfa_enabled_at", "updated_at").
+		WherePK().
+		Update()
+
+	return err
+}
+
+func (u *UserRepo) UpdateDisabled(user *domain.User) (*domain.User, error) {
+	_, err := u.DB.Model(user).
+		Column("disabled_at", "updated_at").
+		WherePK().
+		Returning("*").
+		Update()
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func NewUserRepo(DB *pg.DB) *UserRepo {
+func (u *UserRepo) UpdatePassword(user *domain.User) error {
+	_, err := u.DB.Model(user).
+		Set("password = ?password").
+		Set("updated_at = NOW()").
+		WherePK().
+		Returning("updated_at").
+		Update()
+
+	return err
+}
+
+// Delete removes the user, their data goes with them (ON DELETE CASCADE)
+func (u *UserRepo) Delete(user *domain.User) error {
+	_, err := u.DB.Model(user).WherePK().Delete()
+	return err
+}
+
+// usersLimit is the size of a page of the user search
+const usersLimit = 50
+
+// Search matches the query against the username and email, case insensitive
+func (u *UserRepo) Search(filter domain.UserFilter) ([]*domain.User, error) {
+	var users []*domain.User
+	query := u.DB.Model(&users)
+
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		query.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q.WhereOr("username ILIKE ?", pattern).
+				WhereOr("email ILIKE ?", pattern)
+			return q, nil
+		})
+	}
+
+	if filter.Role != "" {
+		query.Where("role = ?", filter.Role)
+	}
+
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query.Where("disabled_at IS NOT NULL")
+		} else {
+			query.Where("disabled_at IS NULL")
+		}
+	}
+
+	err := query.
+		Order("id ASC").
+		Limit(usersLimit).
+		Offset(filter.Offset).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (u *UserRepo) IncrementTokenGeneration(user *domain.User) error {
+	_, err := u.DB.Model(user).
+		Set("token_generation = token_generation + 1").
+		Set("updated_at = NOW()").
+		WherePK().
+		Returning("*").
+		Update()
+
+	return err
+}
+
+func (u *UserRepo) SetMFALastStep(user *domain.User, step int64) (bool, error) {
+	res, err := u.DB.Model(user).
+		Set("mfa_last_step = ?", step).
+		WherePK().
+		Where("mfa_last_step < ?", step).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	user.MFALastStep = step
+	return true, nil
+}
+
+func NewUserRepo(DB Conn) *UserRepo {
 	return &UserRepo{DB: DB}
 }
+
+// escapeLike escapes the wildcards of LIKE in what the user searches
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// setUserKeys keeps the keys of the unique indexes in sync with the username and email
+func setUserKeys(user *domain.User) {
+	user.UsernameKey = domain.UsernameKey(user.Username)
+	user.EmailKey = domain.EmailKey(user.Email)
+}
+
+// BackfillUserKeys fills the keys of the users created before the migration of the keys, then makes
+// them NOT NULL. The keys can't be computed in SQL, it runs on startup. It fails while two users
+// collide, the identity-collisions command lists them
+func BackfillUserKeys(DB *pg.DB) error {
+	return DB.RunInTransaction(DB.Context(), func(tx *pg.Tx) error {
+		var nullable bool
+		_, err := tx.QueryOne(pg.Scan(&nullable), `
+			SELECT NOT attnotnull FROM pg_attribute
+			WHERE attrelid = 'users'::regclass AND attname = 'username_key'`)
+		if err != nil || !nullable {
+			return err
+		}
+
+		// nobody registers or changes their username or email while the keys are filled
+		if _, err := tx.Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+
+		var users []*domain.User
+		err = tx.Model(&users).
+			Column("id", "username", "email").
+			Where("username_key IS NULL OR email_key IS NULL").
+			Select()
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			setUserKeys(user)
+
+			_, err := tx.Model(user).Column("username_key", "email_key").WherePK().Update()
+			if err != nil {
+				if collision := userUniqueViolation(err); collision != err {
+					return fmt.Errorf("user %d: %w, run the identity-collisions command", user.ID, collision)
+				}
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`ALTER TABLE users
+			ALTER COLUMN username_key SET NOT NULL,
+			ALTER COLUMN email_key SET NOT NULL`)
+		return err
+	})
+}
+
+// userUniqueViolation maps a race between two users taking the same email or username
+func userUniqueViolation(err error) error {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) || !pgErr.IntegrityViolation() {
+		return err
+	}
+
+	switch pgErr.Field('n') {
+	case usersEmailLowerIndex, usersEmailKeyIndex:
+		return domain.ErrUserWithEmailAlreadyExist
+	case usersUsernameLowerIndex, usersUsernameKeyIndex:
+		return domain.ErrUserWithUsernameAlreadyExist
+	}
+
+	return err
+}
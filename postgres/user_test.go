@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"todo/domain"
+)
+
+// pgError is the error of Postgres for a violated constraint
+type pgError struct {
+	index     string
+	integrity bool
+}
+
+func (p pgError) Error() string { return "ERROR #23505 duplicate key value violates unique constraint" }
+
+func (p pgError) Field(field byte) string {
+	if field == 'n' {
+		return p.index
+	}
+	return ""
+}
+
+func (p pgError) IntegrityViolation() bool { return p.integrity }
+
+func TestUserUniqueViolation(t *testing.T) {
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "email key", err: pgError{index: usersEmailKeyIndex, integrity: true}, want: domain.ErrUserWithEmailAlreadyExist},
+		{name: "email lower", err: pgError{index: usersEmailLowerIndex, integrity: true}, want: domain.ErrUserWithEmailAlreadyExist},
+		{name: "username key", err: pgError{index: usersUsernameKeyIndex, integrity: true}, want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "username lower", err: pgError{index: usersUsernameLowerIndex, integrity: true}, want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "wrapped", err: fmt.Errorf("insert: %w", pgError{index: usersUsernameKeyIndex, integrity: true}), want: domain.ErrUserWithUsernameAlreadyExist},
+		{name: "other index", err: pgError{index: "users_pkey", integrity: true}},
+		{name: "not an integrity violation", err: pgError{index: usersEmailKeyIndex}},
+		{name: "not a postgres error", err: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == nil {
+				want = tt.err
+			}
+
+			if got := userUniqueViolation(tt.err); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
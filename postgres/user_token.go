@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"errors"
+	"todo/domain"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+type UserTokenRepo struct {
+	DB Conn
+}
+
+func (u *UserTokenRepo) Create(token *domain.UserToken) (*domain.UserToken, error) {
+	_, err := u.DB.Model(token).Returning("*").Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Get returns the token when it's unused and not expired, without using it
+func (u *UserTokenRepo) Get(purpose, hash string) (*domain.UserToken, error) {
+	token := new(domain.UserToken)
+	err := u.DB.Model(token).
+		Where("purpose = ?", purpose).
+		Where("token_hash = ?", hash).
+		Where("used_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > NOW()").
+		First()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Consume marks the token as used in a single statement, so it can't be used twice by concurrent requests
+func (u *UserTokenRepo) Consume(purpose, hash string) (*domain.UserToken, error) {
+	token := new(domain.UserToken)
+	return consumeUserToken(token, u.DB.Model(token), purpose, hash)
+}
+
+// ConsumeForUser is Consume for the tokens of the user, the ones of the other users are neither found nor used
+func (u *UserTokenRepo) ConsumeForUser(userID int64, purpose, hash string) (*domain.UserToken, error) {
+	token := new(domain.UserToken)
+	return consumeUserToken(token, u.DB.Model(token).Where("user_id = ?", userID), purpose, hash)
+}
+
+func consumeUserToken(token *domain.UserToken, q *orm.Query, purpose, hash string) (*domain.UserToken, error) {
+	res, err := q.
+		Set("used_at = NOW()").
+		Where("purpose = ?", purpose).
+		Where("token_hash = ?", hash).
+		Where("used_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > NOW()").
+		Returning("*").
+		Update()
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, domain.ErrNoResult
+		}
+		return nil, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return nil, domain.ErrNoResult
+	}
+
+	return token, nil
+}
+
+func (u *UserTokenRepo) DeleteByUser(userID int64, purpose string) error {
+	_, err := u.DB.Model((*domain.UserToken)(nil)).
+		Where("user_id = ?", userID).
+		Where("purpose = ?", purpose).
+		Delete()
+
+	return err
+}
+
+func NewUserTokenRepo(DB Conn) *UserTokenRepo {
+	return &UserTokenRepo{DB: DB}
+}
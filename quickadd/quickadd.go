@@ -0,0 +1,384 @@
+// Package quickadd parses the free text of the quick-add box, e.g
+// "Pay rent every 1st #finance !high tomorrow 9am", into the fields of a todo.
+package quickadd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hour used for a due date given without a time ("tomorrow", "friday"...)
+const DefaultHour = 9
+
+// Result is what we understood from the text. The UI shows it back to the user
+type Result struct {
+	Title      string     `json:"title"`
+	Tags       []string   `json:"tags"`
+	Priority   string     `json:"priority,omitempty"`
+	DueAt      *time.Time `json:"dueAt,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"` // RFC 5545 RRULE, e.g FREQ=MONTHLY;BYMONTHDAY=1
+}
+
+var (
+	tagRegexp      = regexp.MustCompile(`^#([\pL\pN_-]+)$`)
+	timeRegexp     = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	ordinalRegexp  = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)$`)
+	isoDateRegexp  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	numberRegexp   = regexp.MustCompile(`^\d+$`)
+	trailingFiller = map[string]bool{"at": true, "on": true, "by": true, "due": true}
+)
+
+var priorities = map[string]string{
+	"high": "high", "h": "high", "1": "high", "urgent": "high",
+	"medium": "medium", "med": "medium", "m": "medium", "2": "medium",
+	"low": "low", "l": "low", "3": "low",
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// the short forms are words too ("Sat nav repair"), they're only days after "on", "next" or "every"
+var shortWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+var rruleDays = map[time.Weekday]string{
+	time.Sunday: "SU", time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE",
+	time.Thursday: "TH", time.Friday: "FR", time.Saturday: "SA",
+}
+
+// they're words of the title too ("Write weekly report"), they're only recurrences at the end of it
+var recurrenceWords = map[string]string{
+	"daily": "FREQ=DAILY", "weekly": "FREQ=WEEKLY", "monthly": "FREQ=MONTHLY", "yearly": "FREQ=YEARLY",
+}
+
+var units = map[string]string{
+	"day": "DAILY", "days": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY",
+}
+
+// parser keeps the state while we walk the words of the text
+type parser struct {
+	now    time.Time // in the location of the user, dates are resolved relative to it
+	words  []string
+	pos    int
+	result Result
+	title  []string
+
+	// the last recurrence word ("weekly") and its index in the title, see recurrenceWords
+	trailingRule string
+	trailingAt   int
+
+	date    *time.Time // day of the due date (midnight)
+	hour    int
+	minute  int
+	hasTime bool
+}
+
+// Parse extracts the todo fields from text. Relative dates ("tomorrow", "friday", "9am")
+// are resolved against now, which must be in the user's location.
+func Parse(text string, now time.Time) Result {
+	p := &parser{
+		now:    now,
+		words:  strings.Fields(text),
+		result: Result{Tags: []string{}},
+	}
+
+	for p.pos < len(p.words) {
+		if !p.parseToken() {
+			p.title = append(p.title, p.words[p.pos])
+			p.pos++
+		}
+	}
+
+	// "Call mom at" -> "Call mom"
+	p.trimFiller()
+
+	// "Standup weekly" repeats, "Write weekly report" doesn't
+	if p.trailingRule != "" && p.result.Recurrence == "" && p.trailingAt == len(p.title)-1 {
+		p.result.Recurrence = p.trailingRule
+		p.title = p.title[:len(p.title)-1]
+		p.trimFiller()
+	}
+
+	p.result.Title = strings.Join(p.title, " ")
+	p.result.DueAt = p.dueAt()
+
+	return p.result
+}
+
+func (p *parser) trimFiller() {
+	for len(p.title) > 0 && trailingFiller[strings.ToLower(p.title[len(p.title)-1])] {
+		p.title = p.title[:len(p.title)-1]
+	}
+}
+
+func (p *parser) word(offset int) string {
+	if p.pos+offset >= len(p.words) {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimRight(p.words[p.pos+offset], ",."))
+}
+
+// parseToken tries every rule at the current word. It moves pos forward and returns true on a match
+func (p *parser) parseToken() bool {
+	raw := p.words[p.pos]
+	w := p.word(0)
+
+	if m := tagRegexp.FindStringSubmatch(raw); m != nil {
+		p.result.Tags = append(p.result.Tags, strings.ToLower(m[1]))
+		p.pos++
+		return true
+	}
+
+	if strings.HasPrefix(w, "!") {
+		if priority, ok := priorities[w[1:]]; ok && p.result.Priority == "" {
+			p.result.Priority = priority
+			p.pos++
+			return true
+		}
+	}
+
+	if (w == "every" || w == "each") && p.result.Recurrence == "" {
+		if rule, n := p.recurrence(); n > 0 {
+			p.result.Recurrence = rule
+			p.pos += n + 1
+			return true
+		}
+	}
+
+	// kept in the title until we know whether more of the title follows
+	if rule, ok := recurrenceWords[w]; ok {
+		p.trailingRule, p.trailingAt = rule, len(p.title)
+		p.title = append(p.title, p.words[p.pos])
+		p.pos++
+		return true
+	}
+
+	if p.date == nil {
+		if date, n := p.parseDate(); n > 0 {
+			p.date = &date
+			p.pos += n
+			return true
+		}
+	}
+
+	if !p.hasTime {
+		// "at 9am" or "9am"
+		offset := 0
+		if w == "at" {
+			offset = 1
+		}
+
+		if hour, minute, ok := parseClock(p.word(offset), offset == 1); ok {
+			p.hour, p.minute, p.hasTime = hour, minute, true
+			p.pos += offset + 1
+			return true
+		}
+
+		if w == "tonight" {
+			p.hour, p.minute, p.hasTime = 20, 0, true
+			today := p.today()
+			if p.date == nil {
+				p.date = &today
+			}
+			p.pos++
+			return true
+		}
+	}
+
+	return false
+}
+
+// recurrence parses the words after "every". It returns the rule and how many words it used
+func (p *parser) recurrence() (string, int) {
+	w := p.word(1)
+
+	if freq, ok := units[w]; ok {
+		return "FREQ=" + freq, 1
+	}
+
+	if day, ok := weekday(w, true); ok {
+		return "FREQ=WEEKLY;BYDAY=" + rruleDays[day], 1
+	}
+
+	if w == "weekday" || w == "weekdays" {
+		return "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", 1
+	}
+
+	// "every 1st", "every 15th" (of the month)
+	if m := ordinalRegexp.FindStringSubmatch(w); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		if day >= 1 && day <= 31 {
+			n := 1
+			if p.word(2) == "of" && p.word(3) == "the" && p.word(4) == "month" {
+				n = 4
+			}
+			return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", day), n
+		}
+	}
+
+	// "every 2 weeks"
+	if numberRegexp.MatchString(w) {
+		if freq, ok := units[p.word(2)]; ok {
+			interval, _ := strconv.Atoi(w)
+			if interval > 0 {
+				return fmt.Sprintf("FREQ=%s;INTERVAL=%d", freq, interval), 2
+			}
+		}
+	}
+
+	return "", 0
+}
+
+// parseDate understands today, tomorrow, weekdays ("friday", "on sat", "next friday"), "in N days/weeks" and ISO dates
+func (p *parser) parseDate() (time.Time, int) {
+	w := p.word(0)
+	today := p.today()
+
+	switch w {
+	case "today":
+		return today, 1
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), 1
+	case "on":
+		if day, ok := weekday(p.word(1), true); ok {
+			return nextWeekday(today, day), 2
+		}
+	case "next":
+		if day, ok := weekday(p.word(1), true); ok {
+			return nextWeekday(today, day), 2
+		}
+		if p.word(1) == "week" {
+			return nextWeekday(today, time.Monday), 2
+		}
+		if p.word(1) == "month" {
+			return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), 2
+		}
+	case "in":
+		if numberRegexp.MatchString(p.word(1)) {
+			n, _ := strconv.Atoi(p.word(1))
+			switch units[p.word(2)] {
+			case "DAILY":
+				return today.AddDate(0, 0, n), 3
+			case "WEEKLY":
+				return today.AddDate(0, 0, 7*n), 3
+			case "MONTHLY":
+				return today.AddDate(0, n, 0), 3
+			case "YEARLY":
+				return today.AddDate(n, 0, 0), 3
+			}
+		}
+	}
+
+	if day, ok := weekday(w, false); ok {
+		return nextWeekday(today, day), 1
+	}
+
+	if isoDateRegexp.MatchString(w) {
+		if date, err := time.ParseInLocation("2006-01-02", w, today.Location()); err == nil {
+			return date, 1
+		}
+	}
+
+	return time.Time{}, 0
+}
+
+// parseClock reads "9am", "9:30pm" and "21:00". A bare number ("9") is only a time after "at"
+func parseClock(w string, afterAt bool) (int, int, bool) {
+	m := timeRegexp.FindStringSubmatch(w)
+	if m == nil || (m[2] == "" && m[3] == "" && !afterAt) {
+		return 0, 0, false
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+
+	if minute > 59 {
+		return 0, 0, false
+	}
+
+	switch m[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+
+		hour = hour % 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, false
+		}
+	}
+
+	return hour, minute, true
+}
+
+// weekday reads the name of a day, the short forms only when short is allowed
+func weekday(w string, short bool) (time.Weekday, bool) {
+	if day, ok := weekdays[w]; ok {
+		return day, true
+	}
+
+	if day, ok := shortWeekdays[w]; ok && short {
+		return day, true
+	}
+
+	return 0, false
+}
+
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+}
+
+// the next day (after today) that falls on the weekday
+func nextWeekday(today time.Time, day time.Weekday) time.Time {
+	days := (int(day) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+
+	return today.AddDate(0, 0, days)
+}
+
+// dueAt combines the date and time we found. A time alone means the next time it happens
+func (p *parser) dueAt() *time.Time {
+	if p.date == nil && !p.hasTime {
+		return nil
+	}
+
+	hour, minute := DefaultHour, 0
+	if p.hasTime {
+		hour, minute = p.hour, p.minute
+	}
+
+	var date time.Time
+	if p.date != nil {
+		date = *p.date
+	} else {
+		date = p.today()
+	}
+
+	due := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location())
+
+	if p.date == nil && !due.After(p.now) {
+		due = due.AddDate(0, 0, 1)
+	}
+
+	return &due
+}
@@ -0,0 +1,88 @@
+package quickadd
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a Wednesday
+	now := time.Date(2024, time.March, 13, 10, 30, 0, 0, paris)
+	at := func(day, hour, minute int) *time.Time {
+		due := time.Date(2024, time.March, day, hour, minute, 0, 0, paris)
+		return &due
+	}
+
+	tests := []struct {
+		text       string
+		title      string
+		tags       []string
+		priority   string
+		dueAt      *time.Time
+		recurrence string
+	}{
+		{text: "Pay rent every 1st", title: "Pay rent", recurrence: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{text: "Pay rent every 1st of the month", title: "Pay rent", recurrence: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{text: "Water plants every 2 weeks", title: "Water plants", recurrence: "FREQ=WEEKLY;INTERVAL=2"},
+		{text: "Gym every sat", title: "Gym", recurrence: "FREQ=WEEKLY;BYDAY=SA"},
+		{text: "Call mom next friday", title: "Call mom", dueAt: at(15, DefaultHour, 0)},
+		{text: "Call mom friday 7pm", title: "Call mom", dueAt: at(15, 19, 0)},
+		{text: "Renew passport in 3 days", title: "Renew passport", dueAt: at(16, DefaultHour, 0)},
+		{text: "Standup 9am", title: "Standup", dueAt: at(14, 9, 0)},
+		{text: "Lunch 12:15pm", title: "Lunch", dueAt: at(13, 12, 15)},
+		{text: "Call Bob at 9", title: "Call Bob", dueAt: at(14, 9, 0)},
+		{text: "Call Bob at 11", title: "Call Bob", dueAt: at(13, 11, 0)},
+		{text: "Movie tonight", title: "Movie", dueAt: at(13, 20, 0)},
+		{text: "Dentist tomorrow 9:30pm", title: "Dentist", dueAt: at(14, 21, 30)},
+		{text: "Submit report 2024-03-20", title: "Submit report", dueAt: at(20, DefaultHour, 0)},
+		{text: "Fix prod !high", title: "Fix prod", priority: "high"},
+		{text: "Buy milk #Groceries #home", title: "Buy milk", tags: []string{"groceries", "home"}},
+		{text: "Pay rent every 1st #finance !high tomorrow 9am", title: "Pay rent", tags: []string{"finance"},
+			priority: "high", dueAt: at(14, 9, 0), recurrence: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{text: "Call mom at", title: "Call mom"},
+		{text: "Send invoice due", title: "Send invoice"},
+		{text: "Sat nav repair", title: "Sat nav repair"},
+		{text: "Wed planning with Sun team", title: "Wed planning with Sun team"},
+		{text: "Dinner on sat", title: "Dinner", dueAt: at(16, DefaultHour, 0)},
+		{text: "Dinner next sat", title: "Dinner", dueAt: at(16, DefaultHour, 0)},
+		{text: "Standup weekly", title: "Standup", recurrence: "FREQ=WEEKLY"},
+		{text: "Standup daily 9am #work", title: "Standup", tags: []string{"work"}, dueAt: at(14, 9, 0), recurrence: "FREQ=DAILY"},
+		{text: "Write weekly report", title: "Write weekly report"},
+		{text: "Write weekly report monthly", title: "Write weekly report", recurrence: "FREQ=MONTHLY"},
+		{text: "Review monthly budget every friday", title: "Review monthly budget", recurrence: "FREQ=WEEKLY;BYDAY=FR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Parse(tt.text, now)
+
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			if strings.Join(got.Tags, ",") != strings.Join(tt.tags, ",") {
+				t.Errorf("tags = %v, want %v", got.Tags, tt.tags)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("priority = %q, want %q", got.Priority, tt.priority)
+			}
+			if got.Recurrence != tt.recurrence {
+				t.Errorf("recurrence = %q, want %q", got.Recurrence, tt.recurrence)
+			}
+
+			switch {
+			case got.DueAt == nil && tt.dueAt == nil:
+			case got.DueAt == nil || tt.dueAt == nil || !got.DueAt.Equal(*tt.dueAt):
+				t.Errorf("dueAt = %v, want %v", got.DueAt, tt.dueAt)
+			case got.DueAt.Location() != paris:
+				t.Errorf("dueAt is in %v, want the location of now", got.DueAt.Location())
+			}
+		})
+	}
+}
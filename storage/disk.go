@@ -0,0 +1,44 @@
+// Package storage has the implementations of domain.FileStore
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Disk keeps the files in a directory. Several instances need a shared directory
+type Disk struct {
+	Dir string
+}
+
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &Disk{Dir: dir}, nil
+}
+
+// path keeps the files in the directory, whatever the name
+func (d *Disk) path(name string) string {
+	return filepath.Join(d.Dir, filepath.Base(name))
+}
+
+func (d *Disk) Save(name string, data []byte) error {
+	return ioutil.WriteFile(d.path(name), data, 0600)
+}
+
+func (d *Disk) Open(name string) (io.ReadCloser, error) {
+	return os.Open(d.path(name))
+}
+
+// Delete doesn't fail when the file is already gone
+func (d *Disk) Delete(name string) error {
+	err := os.Remove(d.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A minimal CBOR (RFC 8949) decoder, enough for attestation objects and COSE keys.
+// Maps decode to map[interface{}]interface{} with int64 or string keys, unsigned and
+// negative integers to int64, byte strings to []byte and text strings to string.
+// Indefinite lengths, tags and floats are not used by WebAuthn and are rejected.
+
+var errCBOR = errors.New("webauthn: malformed cbor")
+
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first item of data and returns the bytes after it
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth || len(data) == 0 {
+		return nil, nil, errCBOR
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	// simple values have no argument to read
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+		return nil, nil, errCBOR
+	}
+
+	arg, data, err := cborArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, nil, errCBOR
+		}
+		return int64(arg), data, nil
+
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(arg), data, nil
+
+	case 2, 3:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		value := data[:arg]
+		if major == 3 {
+			return string(value), data[arg:], nil
+		}
+		return append([]byte(nil), value...), data[arg:], nil
+
+	case 4:
+		// every item takes at least one byte
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errCBOR
+			}
+
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	}
+
+	return nil, nil, errCBOR
+}
+
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+
+	return 0, nil, errCBOR
+}
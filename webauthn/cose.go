@@ -0,0 +1,134 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+)
+
+// COSE algorithms (RFC 8152) we accept, in order of preference
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1 // n for RSA
+	coseX   = -2 // e for RSA
+	coseY   = -3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// PublicKey is a credential public key decoded from its COSE form
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey decodes a COSE_Key, the format we store the credentials in
+func ParsePublicKey(cose []byte) (*PublicKey, error) {
+	key, _, err := parsePublicKey(cose)
+	return key, err
+}
+
+// parsePublicKey also returns the bytes after the key, e.g the extensions of the authenticator data
+func parsePublicKey(data []byte) (*PublicKey, []byte, error) {
+	item, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, ErrUnsupportedKey
+	}
+
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, nil, ErrUnsupportedKey
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, nil, ErrUnsupportedKey
+		}
+
+		return &PublicKey{Algorithm: alg, Key: key}, rest, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, nil, ErrUnsupportedKey
+		}
+
+		return &PublicKey{Algorithm: alg, Key: ed25519.PublicKey(x)}, rest, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseCrv)].([]byte)
+		e, _ := m[int64(coseX)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, nil, ErrUnsupportedKey
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+
+		return &PublicKey{Algorithm: alg, Key: key}, rest, nil
+	}
+
+	return nil, nil, ErrUnsupportedKey
+}
+
+// Verify checks the signature of the authenticator over data
+func (k *PublicKey) Verify(data, signature []byte) error {
+	ok := false
+
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, signature)
+
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
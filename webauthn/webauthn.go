@@ -0,0 +1,414 @@
+// Package webauthn implements the relying party side of the WebAuthn ceremonies
+// (https://www.w3.org/TR/webauthn-2/) used to register passkeys and log in with them.
+// It doesn't verify attestation certificate chains: we ask for "none" attestation, the
+// credential is trusted because the logged in user registered it.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidClientData      = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch      = errors.New("webauthn: challenge does not match")
+	ErrOriginNotAllowed       = errors.New("webauthn: origin not allowed")
+	ErrInvalidAuthData        = errors.New("webauthn: invalid authenticator data")
+	ErrRPIDMismatch           = errors.New("webauthn: authenticator data is for another relying party")
+	ErrUserNotPresent         = errors.New("webauthn: user presence is required")
+	ErrUserNotVerified        = errors.New("webauthn: user verification is required")
+	ErrUnsupportedKey         = errors.New("webauthn: unsupported credential key")
+	ErrUnsupportedAttestation = errors.New("webauthn: unsupported attestation format")
+	ErrInvalidSignature       = errors.New("webauthn: invalid signature")
+	ErrSignCountNotIncreased  = errors.New("webauthn: sign counter did not increase, the authenticator may be cloned")
+)
+
+// authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// Timeout of the ceremonies in the browser, in milliseconds
+const Timeout = 5 * 60 * 1000
+
+type RelyingParty struct {
+	ID      string   // domain of the web app, e.g todo.example.com
+	Name    string   // shown by the authenticator
+	Origins []string // origins allowed to run the ceremonies, e.g https://todo.example.com
+
+	RequireUserVerification bool
+}
+
+func (rp *RelyingParty) userVerification() string {
+	if rp.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+// URLEncodedBase64 is a []byte that travels as base64url, as the browsers send them
+type URLEncodedBase64 []byte
+
+func (b URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+func NewChallenge() (URLEncodedBase64, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBase64 `json:"id"` // user handle, must not contain personal information
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string           `json:"type"`
+	ID         URLEncodedBase64 `json:"id"`
+	Transports []string         `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the PublicKeyCredentialCreationOptions for navigator.credentials.create
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              URLEncodedBase64       `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the PublicKeyCredentialRequestOptions for navigator.credentials.get
+type RequestOptions struct {
+	Challenge        URLEncodedBase64       `json:"challenge"`
+	Timeout          int                    `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"` // empty for discoverable credentials
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions for a new credential of the user, excluding the ones already registered
+func (rp *RelyingParty) CreationOptions(user UserEntity, challenge URLEncodedBase64, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: "public-key", Algorithm: alg}
+	}
+
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            Timeout,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+}
+
+func (rp *RelyingParty) RequestOptions(challenge URLEncodedBase64, allow []CredentialDescriptor) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout,
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: rp.userVerification(),
+	}
+}
+
+// AttestationResponse is the PublicKeyCredential returned by navigator.credentials.create
+type AttestationResponse struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AttestationObject URLEncodedBase64 `json:"attestationObject"`
+		Transports        []string         `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBase64 `json:"authenticatorData"`
+		Signature         URLEncodedBase64 `json:"signature"`
+		UserHandle        URLEncodedBase64 `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is what we keep of a registered credential
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Type != ceremony {
+		return ErrInvalidClientData
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+
+	return ErrOriginNotAllowed
+}
+
+type authenticatorData struct {
+	raw       []byte
+	flags     byte
+	signCount uint32
+
+	// only when flagAttestedCredData is set
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (rp *RelyingParty) parseAuthData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, ErrInvalidAuthData
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(raw[:32], rpIDHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+
+	data := &authenticatorData{
+		raw:       raw,
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+
+	if rp.RequireUserVerification && data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	if data.flags&flagAttestedCredData == 0 {
+		return data, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidAuthData
+	}
+
+	data.aaguid = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if idLength > 1023 || len(rest) < idLength {
+		return nil, ErrInvalidAuthData
+	}
+
+	data.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// the key is followed by the extensions, if any
+	_, after, err := parsePublicKey(rest)
+	if err != nil {
+		return nil, err
+	}
+	data.publicKey = rest[:len(rest)-len(after)]
+
+	return data, nil
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create to the challenge
+func (rp *RelyingParty) VerifyRegistration(response AttestationResponse, challenge []byte) (*Credential, error) {
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	item, _, err := decodeCBOR(response.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, errCBOR
+	}
+
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+
+	authData, err := rp.parseAuthData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.credentialID == nil {
+		return nil, ErrInvalidAuthData
+	}
+
+	if !bytes.Equal(authData.credentialID, response.RawID) {
+		return nil, ErrInvalidAuthData
+	}
+
+	publicKey, err := ParsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyAttestation(format, statement, authData, publicKey, response.Response.ClientDataJSON); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// verifyAttestation accepts "none" and checks the signature of "packed", without trusting the certificate
+func verifyAttestation(format string, statement map[interface{}]interface{}, authData *authenticatorData, key *PublicKey, clientDataJSON []byte) error {
+	switch format {
+	case "none":
+		return nil
+
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+
+		signer := key
+		if chain, ok := statement["x5c"].([]interface{}); ok && len(chain) > 0 {
+			der, _ := chain[0].([]byte)
+			certificate, err := x509.ParseCertificate(der)
+			if err != nil {
+				return ErrUnsupportedAttestation
+			}
+			signer = &PublicKey{Algorithm: alg, Key: certificate.PublicKey}
+		} else if alg != key.Algorithm {
+			return ErrUnsupportedAttestation
+		}
+
+		return signer.Verify(signedData(authData.raw, clientDataJSON), signature)
+	}
+
+	return ErrUnsupportedAttestation
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get with the stored credential
+// and returns its new sign counter
+func (rp *RelyingParty) VerifyAssertion(response AssertionResponse, challenge []byte, credential *Credential) (uint32, error) {
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthData(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := publicKey.Verify(signedData(authData.raw, response.Response.ClientDataJSON), response.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	// authenticators without a counter always send 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountNotIncreased
+	}
+
+	return authData.signCount, nil
+}
+
+// signedData is what the authenticator signs: the authenticator data and the hash of the client data
+func signedData(authData, clientDataJSON []byte) []byte {
+	hash := sha256.Sum256(clientDataJSON)
+	return append(append([]byte(nil), authData...), hash[:]...)
+}
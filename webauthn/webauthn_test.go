@@ -0,0 +1,154 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"todo/webauthn"
+	"todo/webauthn/webauthntest"
+)
+
+const origin = "https://todo.example.com"
+
+func relyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{
+		ID:      "todo.example.com",
+		Name:    "Todo",
+		Origins: []string{origin},
+	}
+}
+
+func challenge(t *testing.T) webauthn.URLEncodedBase64 {
+	t.Helper()
+
+	c, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// register runs the registration ceremony and returns the stored credential
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	c := challenge(t)
+	response, err := authenticator.Create(rp.CreationOptions(webauthn.UserEntity{ID: []byte{1}, Name: "bob@example.com"}, c, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential, err := rp.VerifyRegistration(*response, c)
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	return credential
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	rp := relyingParty()
+
+	credential := register(t, rp, webauthntest.New(origin))
+	if len(credential.ID) == 0 || len(credential.PublicKey) == 0 {
+		t.Fatalf("credential without id or key: %+v", credential)
+	}
+
+	tests := []struct {
+		name   string
+		origin string
+		other  bool // answer another challenge than the one verified
+		want   error
+	}{
+		{name: "bad challenge", origin: origin, other: true, want: webauthn.ErrChallengeMismatch},
+		{name: "bad origin", origin: "https://evil.example.com", want: webauthn.ErrOriginNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := challenge(t)
+			answered := c
+			if tt.other {
+				answered = challenge(t)
+			}
+
+			response, err := webauthntest.New(tt.origin).Create(rp.CreationOptions(webauthn.UserEntity{ID: []byte{1}}, answered, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := rp.VerifyRegistration(*response, c); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	rp := relyingParty()
+	authenticator := webauthntest.New(origin)
+	credential := register(t, rp, authenticator)
+
+	assert := func(a *webauthntest.Authenticator, answered, verified webauthn.URLEncodedBase64, tamper func(*webauthn.AssertionResponse)) (uint32, error) {
+		response, err := a.Get(rp.RequestOptions(answered, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tamper != nil {
+			tamper(response)
+		}
+
+		return rp.VerifyAssertion(*response, verified, credential)
+	}
+
+	c := challenge(t)
+	signCount, err := assert(authenticator, c, c, nil)
+	if err != nil {
+		t.Fatalf("assertion failed: %v", err)
+	}
+	if signCount <= credential.SignCount {
+		t.Fatalf("sign count %d did not increase from %d", signCount, credential.SignCount)
+	}
+	credential.SignCount = signCount
+
+	t.Run("bad challenge", func(t *testing.T) {
+		if _, err := assert(authenticator, challenge(t), challenge(t), nil); !errors.Is(err, webauthn.ErrChallengeMismatch) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrChallengeMismatch)
+		}
+	})
+
+	t.Run("bad origin", func(t *testing.T) {
+		clone := authenticator.Clone()
+		clone.Origin = "https://evil.example.com"
+
+		c := challenge(t)
+		if _, err := assert(clone, c, c, nil); !errors.Is(err, webauthn.ErrOriginNotAllowed) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrOriginNotAllowed)
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		c := challenge(t)
+		_, err := assert(authenticator, c, c, func(response *webauthn.AssertionResponse) {
+			response.Response.Signature[len(response.Response.Signature)-1] ^= 0xff
+		})
+		if !errors.Is(err, webauthn.ErrInvalidSignature) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrInvalidSignature)
+		}
+	})
+
+	t.Run("sign count going backwards", func(t *testing.T) {
+		clone := authenticator.Clone()
+
+		c := challenge(t)
+		signCount, err := assert(authenticator, c, c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		credential.SignCount = signCount
+
+		c = challenge(t)
+		if _, err := assert(clone, c, c, nil); !errors.Is(err, webauthn.ErrSignCountNotIncreased) {
+			t.Errorf("got %v, want %v", err, webauthn.ErrSignCountNotIncreased)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+// Package webauthntest provides a software authenticator to run the WebAuthn ceremonies
+// without a browser, e.g in tests or scripts
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"todo/webauthn"
+)
+
+var ErrNoCredential = errors.New("webauthntest: no credential for this relying party")
+
+// Authenticator is a software authenticator with ES256 discoverable credentials. It always
+// reports user presence and verification, and increases its sign counter on every assertion
+type Authenticator struct {
+	Origin string // origin the "browser" reports in the client data
+
+	credentials []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Create answers navigator.credentials.create with a "none" attestation
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.AttestationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	cred := &credential{
+		id:         id,
+		rpID:       options.RP.ID,
+		userHandle: options.User.ID,
+		key:        key,
+	}
+
+	// attested credential data: aaguid (zeros), length of the id, id and the COSE key
+	attested := make([]byte, 16, 16+2+len(id))
+	attested = append(attested, byte(len(id)>>8), byte(len(id)))
+	attested = append(attested, id...)
+	attested = append(attested, coseKey(&key.PublicKey)...)
+
+	authData := authenticatorData(cred, 0x01|0x04|0x40, attested)
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.credentials = append(a.credentials, cred)
+
+	response := &webauthn.AttestationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(id),
+		RawID: id,
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AttestationObject = encodeMap([]interface{}{
+		"fmt", "none",
+		"attStmt", map[string]interface{}{},
+		"authData", authData,
+	})
+	response.Response.Transports = []string{"internal"}
+
+	return response, nil
+}
+
+// Get answers navigator.credentials.get with the first allowed credential,
+// or any credential of the relying party when the list is empty
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	cred := a.find(options)
+	if cred == nil {
+		return nil, ErrNoCredential
+	}
+
+	cred.signCount++
+	authData := authenticatorData(cred, 0x01|0x04, nil)
+
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), hash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	response := &webauthn.AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawID: cred.id,
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AuthenticatorData = authData
+	response.Response.Signature = signature
+	response.Response.UserHandle = cred.userHandle
+
+	return response, nil
+}
+
+// Clone copies the authenticator with its keys and counters, like a cloned security key:
+// the counters of the copies move on separately
+func (a *Authenticator) Clone() *Authenticator {
+	clone := &Authenticator{Origin: a.Origin}
+	for _, cred := range a.credentials {
+		copied := *cred
+		clone.credentials = append(clone.credentials, &copied)
+	}
+
+	return clone
+}
+
+func (a *Authenticator) find(options *webauthn.RequestOptions) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID != options.RPID {
+			continue
+		}
+
+		if len(options.AllowCredentials) == 0 {
+			return cred
+		}
+
+		for _, allowed := range options.AllowCredentials {
+			if string(allowed.ID) == string(cred.id) {
+				return cred
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.Origin,
+	})
+}
+
+func authenticatorData(cred *credential, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], cred.signCount)
+
+	return append(data, attested...)
+}
+
+func coseKey(key *ecdsa.PublicKey) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	return encodeMap([]interface{}{
+		int64(1), int64(2), // kty: EC2
+		int64(3), webauthn.AlgES256,
+		int64(-1), int64(1), // crv: P-256
+		int64(-2), x,
+		int64(-3), y,
+	})
+}
+
+// encodeMap encodes the key value pairs as a CBOR map, keeping their order
+func encodeMap(pairs []interface{}) []byte {
+	out := cborHeader(5, uint64(len(pairs)/2))
+	for _, item := range pairs {
+		out = append(out, encodeItem(item)...)
+	}
+	return out
+}
+
+func encodeItem(item interface{}) []byte {
+	switch v := item.(type) {
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case map[string]interface{}:
+		pairs := make([]interface{}, 0, len(v)*2)
+		for key, value := range v {
+			pairs = append(pairs, key, value)
+		}
+		return encodeMap(pairs)
+	}
+
+	panic("webauthntest: cannot encode the item")
+}
+
+func cborHeader(major byte, arg uint64) []byte {
+	major <<= 5
+
+	switch {
+	case arg < 24:
+		return []byte{major | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major | 24, byte(arg)}
+	case arg <= 0xffff:
+		return []byte{major | 25, byte(arg >> 8), byte(arg)}
+	default:
+		b := make([]byte, 5)
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(arg))
+		return b
+	}
+}